@@ -0,0 +1,96 @@
+package dotsqlx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// annotationRe matches a `-- key: value` comment line.
+var annotationRe = regexp.MustCompile(`^\s*--\s*([a-z][a-z0-9_-]*):\s*(.*?)\s*$`)
+
+// Annotation is a `-- key: value` comment line placed at the beginning of
+// a named query, right after its `-- name:` tag.
+type Annotation struct {
+	Key   string
+	Value string
+}
+
+// Annotations is a list of named query annotations in their source order.
+type Annotations []Annotation
+
+// ParseAnnotations returns the annotations placed at the beginning of the
+// query.
+func ParseAnnotations(query string) Annotations {
+	var aa Annotations
+
+	for _, line := range strings.Split(query, "\n") {
+		m := annotationRe.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+
+		aa = append(aa, Annotation{Key: m[1], Value: m[2]})
+	}
+
+	return aa
+}
+
+// Get returns the value of the first annotation with the key.
+func (aa Annotations) Get(key string) string {
+	for _, a := range aa {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// Values returns the values of all annotations with the key.
+func (aa Annotations) Values(key string) []string {
+	var vv []string
+	for _, a := range aa {
+		if a.Key == key {
+			vv = append(vv, a.Value)
+		}
+	}
+
+	return vv
+}
+
+// Tags returns the comma separated values of all `-- tags:` annotations.
+func (aa Annotations) Tags() []string {
+	var tt []string
+	for _, v := range aa.Values("tags") {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tt = append(tt, t)
+			}
+		}
+	}
+
+	return tt
+}
+
+// Annotations returns the annotations of dotsql named query.
+func (d DotSqlx) Annotations(name string) (Annotations, error) {
+	query, err := d.resolve("", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.annotations(name, query), nil
+}
+
+// annotations returns the annotations of the resolved query, including the
+// ones its source holds besides the SQL, e.g. the metadata passed to
+// Registry.Add, unless the queries have been copied from the source.
+func (d DotSqlx) annotations(name, query string) Annotations {
+	if as, ok := d.Source().(annotatedSource); ok && d.queries == nil {
+		if q, ok := as.Get(name); ok {
+			return q.Annotations
+		}
+	}
+
+	return ParseAnnotations(query)
+}
@@ -0,0 +1,42 @@
+package dotsqlx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	assert.Nil(t, ParseAnnotations("SELECT 1"))
+
+	aa := ParseAnnotations("-- tags: admin, reports\n--param: id int64\n-- param: name string\nSELECT 1\n-- returns: one")
+	assert.Equal(t, Annotations{
+		{Key: "tags", Value: "admin, reports"},
+		{Key: "param", Value: "id int64"},
+		{Key: "param", Value: "name string"},
+	}, aa)
+	assert.Equal(t, "id int64", aa.Get("param"))
+	assert.Zero(t, aa.Get("returns"))
+	assert.Equal(t, []string{"id int64", "name string"}, aa.Values("param"))
+	assert.Equal(t, []string{"admin", "reports"}, aa.Tags())
+}
+
+func TestAnnotations(t *testing.T) {
+	tr := NewTracker()
+	dot := newDot(t, `
+-- name: select
+-- tags: admin
+SELECT 1`).WithTracker(tr)
+
+	// query not found
+	aa, err := dot.Annotations("select123")
+	assert.Nil(t, aa)
+	assert.NotNil(t, err)
+
+	// successful call
+	aa, err = dot.Annotations("select")
+	require.Nil(t, err)
+	assert.Equal(t, Annotations{{Key: "tags", Value: "admin"}}, aa)
+	assert.Empty(t, tr.Executed())
+}
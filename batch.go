@@ -0,0 +1,185 @@
+package dotsqlx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNoValues is returned when the VALUES clause of a named query cannot be
+// found.
+var ErrNoValues = errors.New("dotsqlx: VALUES clause not found")
+
+// ErrNamedOutsideValues is returned when a named query passed to
+// NamedExecBatch has named parameters outside its VALUES(...) clause.
+var ErrNamedOutsideValues = errors.New("dotsqlx: named parameters outside the VALUES clause")
+
+// NamedExecBatch binds every element of arg, which must be a slice of
+// structs or maps, to the VALUES(...) clause of dotsql named query and
+// executes it in multi-row form. At most batchSize elements are inserted
+// per statement; the batch is reduced further to respect the bound
+// parameters limit of the executor's driver. If batchSize is zero or
+// negative, only the driver's limit is used. If dbx is a transaction, all
+// batches are executed inside it. The total number of affected rows is
+// returned; if an error occurs, it is returned along with the rows affected
+// by the batches executed before it, which are not rolled back unless dbx
+// is a transaction.
+//
+// Named parameters are only supported inside the VALUES(...) clause and are
+// mapped with jmoiron/sqlx's default name mapper. ErrNamedOutsideValues is
+// returned if there are any outside of it, e.g. in an ON CONFLICT clause.
+func (d DotSqlx) NamedExecBatch(ctx context.Context, dbx sqlx.ExtContext, name string, arg interface{}, batchSize int) (int64, error) {
+	query, err := d.rawNamed(dbx, name)
+	if err != nil {
+		return 0, err
+	}
+
+	prefix, tuple, suffix, err := splitValues(query)
+	if err != nil {
+		return 0, err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	if v.Kind() != reflect.Slice {
+		return 0, errors.New("dotsqlx: arg must be a slice")
+	}
+
+	var (
+		total int64
+		rows  []string
+		args  []interface{}
+		limit = MaxParams(dbx.DriverName())
+	)
+
+	flush := func() error {
+		if len(rows) == 0 {
+			return nil
+		}
+
+		q := prefix + strings.Join(rows, ", ") + suffix
+		res, err := dbx.ExecContext(ctx, dbx.Rebind(q), args...)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		total += n
+		rows, args = rows[:0], args[:0]
+		return nil
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		row, rargs, err := sqlx.Named(tuple, v.Index(i).Interface())
+		if err != nil {
+			return total, err
+		}
+
+		if len(rows) > 0 && (len(rows) == batchSize || len(args)+len(rargs) > limit) {
+			if err = flush(); err != nil {
+				return total, err
+			}
+		}
+
+		rows = append(rows, row)
+		args = append(args, rargs...)
+	}
+
+	err = flush()
+
+	return total, err
+}
+
+// splitValues splits the query into the part preceding the VALUES(...)
+// tuple, the tuple itself and the remaining part of the query. Keywords and
+// parentheses inside string literals, quoted identifiers and comments are
+// ignored.
+func splitValues(query string) (string, string, string, error) {
+	tt, ok := lex(query)
+	if !ok {
+		return "", "", "", ErrNoValues
+	}
+
+	start, depth, pos := -1, 0, 0
+	for _, t := range tt {
+		if t.kind != tokenCode {
+			pos += len(t.text)
+			continue
+		}
+
+		for i := 0; i < len(t.text); i++ {
+			if start < 0 {
+				if j := valuesTuple(t.text, i); j >= 0 {
+					start, i = pos+j, j
+					depth = 1
+				}
+
+				continue
+			}
+
+			switch t.text[i] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth > 0 {
+					continue
+				}
+
+				end := pos + i + 1
+				prefix, suffix := query[:start], query[end:]
+				if hasNamed(prefix) || hasNamed(suffix) {
+					return "", "", "", ErrNamedOutsideValues
+				}
+
+				return prefix, query[start:end], suffix, nil
+			}
+		}
+
+		pos += len(t.text)
+	}
+
+	return "", "", "", ErrNoValues
+}
+
+// valuesTuple returns the index of the parenthesis opening the tuple if the
+// code has the VALUES keyword at i or -1 if it doesn't.
+func valuesTuple(code string, i int) int {
+	const kw = "VALUES"
+
+	if (i > 0 && isWordByte(code[i-1])) || len(code)-i < len(kw) || !strings.EqualFold(code[i:i+len(kw)], kw) {
+		return -1
+	}
+
+	j := i + len(kw)
+	if j < len(code) && isWordByte(code[j]) {
+		return -1
+	}
+
+	for j < len(code) && isSpace(rune(code[j])) {
+		j++
+	}
+
+	if j < len(code) && code[j] == '(' {
+		return j
+	}
+
+	return -1
+}
+
+// hasNamed reports whether the query has any named bindvars.
+func hasNamed(query string) bool {
+	for _, bt := range BindTypes(query) {
+		if bt == sqlx.NAMED {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,121 @@
+package dotsqlx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const batchQueries = `
+-- name: insert_event
+INSERT INTO events (id, name) VALUES (:id, :name)
+
+-- name: insert_event_ignore
+INSERT OR IGNORE INTO events (id, name) VALUES(:id, lower(:name))
+
+-- name: insert_event_invalid
+INSERT INTO missing (id, name) VALUES (:id, :name)
+
+-- name: select_events
+SELECT id, name FROM events`
+
+type event struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestSplitValues(t *testing.T) {
+	_, _, _, err := splitValues("SELECT 1")
+	assert.Equal(t, ErrNoValues, err)
+
+	_, _, _, err = splitValues("INSERT INTO t (a) VALUES (:a")
+	assert.Equal(t, ErrNoValues, err)
+
+	prefix, tuple, suffix, err := splitValues("INSERT INTO t (a, b) values (:a, coalesce(:b, ')')) RETURNING a")
+	require.Nil(t, err)
+	assert.Equal(t, "INSERT INTO t (a, b) values ", prefix)
+	assert.Equal(t, "(:a, coalesce(:b, ')'))", tuple)
+	assert.Equal(t, " RETURNING a", suffix)
+
+	prefix, tuple, suffix, err = splitValues("INSERT INTO \"values\" (a) VALUES\n(:a) ON CONFLICT (a) DO UPDATE SET a = excluded.a::int")
+	require.Nil(t, err)
+	assert.Equal(t, "INSERT INTO \"values\" (a) VALUES\n", prefix)
+	assert.Equal(t, "(:a)", tuple)
+	assert.Equal(t, " ON CONFLICT (a) DO UPDATE SET a = excluded.a::int", suffix)
+
+	_, _, _, err = splitValues("INSERT INTO t (a, b) VALUES (:a, :b) ON CONFLICT (a) DO UPDATE SET b = :b")
+	assert.Equal(t, ErrNamedOutsideValues, err)
+
+	_, _, _, err = splitValues("WITH x AS (SELECT :a) INSERT INTO t (a) VALUES (:a)")
+	assert.Equal(t, ErrNamedOutsideValues, err)
+}
+
+func TestNamedExecBatch(t *testing.T) {
+	dot := newDot(t, batchQueries)
+	db := newDB(t, 0)
+	defer db.Close()
+
+	db.MustExec("CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT)")
+
+	ee := make([]event, 1500)
+	for i := range ee {
+		ee[i] = event{ID: i, Name: "Event"}
+	}
+
+	// query not found
+	n, err := dot.NamedExecBatch(context.Background(), db, "insert_event123", ee, 100)
+	assert.Zero(t, n)
+	assert.NotNil(t, err)
+
+	// VALUES clause not found
+	n, err = dot.NamedExecBatch(context.Background(), db, "select_events", ee, 100)
+	assert.Zero(t, n)
+	assert.Equal(t, ErrNoValues, err)
+
+	// invalid arg
+	n, err = dot.NamedExecBatch(context.Background(), db, "insert_event", ee[0], 100)
+	assert.Zero(t, n)
+	assert.NotNil(t, err)
+
+	// error returned by db
+	n, err = dot.NamedExecBatch(context.Background(), db, "insert_event_invalid", ee, 100)
+	assert.Zero(t, n)
+	assert.NotNil(t, err)
+
+	// error returned by db after some batches
+	db.MustExec("CREATE TRIGGER abort_insert BEFORE INSERT ON events WHEN new.id = 25 BEGIN SELECT RAISE(ABORT, 'aborted'); END")
+
+	n, err = dot.NamedExecBatch(context.Background(), db, "insert_event", ee[:50], 10)
+	assert.Equal(t, int64(20), n)
+	assert.NotNil(t, err)
+
+	db.MustExec("DROP TRIGGER abort_insert")
+	db.MustExec("DELETE FROM events")
+
+	// successful call inside a transaction
+	tx := db.MustBegin()
+	n, err = dot.NamedExecBatch(context.Background(), tx, "insert_event", ee[:10], 3)
+	require.Nil(t, err)
+	assert.Equal(t, int64(10), n)
+	require.Nil(t, tx.Rollback())
+
+	// successful call limited by driver
+	n, err = dot.NamedExecBatch(context.Background(), db, "insert_event", ee, 0)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1500), n)
+
+	// successful call with maps
+	n, err = dot.NamedExecBatch(context.Background(), db, "insert_event_ignore", []map[string]interface{}{
+		{"id": 1, "name": "A"},
+		{"id": 2000, "name": "B"},
+	}, 10)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+
+	var res []event
+	require.Nil(t, dot.Select(db, &res, "select_events"))
+	require.Len(t, res, 1501)
+	assert.Equal(t, event{ID: 2000, Name: "b"}, res[1500])
+}
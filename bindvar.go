@@ -0,0 +1,254 @@
+package dotsqlx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrMixedBindTypes is returned when a query uses more than one bindvar
+// style.
+var ErrMixedBindTypes = errors.New("dotsqlx: mixed bindvar styles")
+
+// BindType returns the jmoiron/sqlx bindvar type used by the query or
+// sqlx.UNKNOWN if it has no bindvars. ErrMixedBindTypes is returned if the
+// query uses more than one style.
+func BindType(query string) (int, error) {
+	bb := BindTypes(query)
+
+	switch len(bb) {
+	case 0:
+		return sqlx.UNKNOWN, nil
+	case 1:
+		return bb[0], nil
+	}
+
+	return sqlx.UNKNOWN, ErrMixedBindTypes
+}
+
+// RebindQuery converts the positional bindvars of the query to bindType.
+// Unlike jmoiron/sqlx's Rebind(), bindvars inside string literals, quoted
+// identifiers and comments are left intact and queries already written
+// with `$1` or `@p1` bindvars are converted as long as the bindvars are
+// numbered sequentially. Queries using `:name` bindvars are meant for
+// named execution and are returned unchanged.
+func RebindQuery(bindType int, query string) (string, error) {
+	bt, err := BindType(query)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case bt == sqlx.UNKNOWN, bt == sqlx.NAMED, bt == bindType:
+		return query, nil
+	case bt == sqlx.DOLLAR, bt == sqlx.AT:
+		if query, err = unnumber(bt, query); err != nil {
+			return "", err
+		}
+
+		for _, left := range BindTypes(query) {
+			if left == bt {
+				return "", fmt.Errorf("dotsqlx: %s bindvars cannot be converted", bindTypeNames[bt])
+			}
+		}
+	}
+
+	return renumber(bindType, query), nil
+}
+
+// RebindAll returns a copy of DotSqlx with all queries converted to
+// bindType once, e.g. sqlx.BindType(db.DriverName()), so that they don't
+// need to be rebound on every execution. A *QueryError is returned for the
+// first query, in name order, that cannot be converted. The copy holds a
+// snapshot of the queries, so later changes to a Registry are not visible
+// to it.
+func (d DotSqlx) RebindAll(bindType int) (*DotSqlx, error) {
+	return d.mapQueries(func(query string) (string, error) {
+		return RebindQuery(bindType, query)
+	})
+}
+
+// in is a wrapper for jmoiron/sqlx's In() that also expands queries
+// written, or rebound, with `$1` or `@p1` bindvars, which keep their style.
+// As with In(), the query and arguments are returned unchanged if no
+// argument has to be expanded, so the bindvars are only checked when it
+// does.
+func in(query string, args ...interface{}) (string, []interface{}, error) {
+	if !anyExpandable(args) {
+		return query, args, nil
+	}
+
+	bt, err := BindType(query)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if bt != sqlx.DOLLAR && bt != sqlx.AT {
+		return sqlx.In(query, args...)
+	}
+
+	if query, err = unnumber(bt, query); err != nil {
+		return "", nil, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return renumber(bt, query), args, nil
+}
+
+// anyExpandable reports whether any argument would be expanded by
+// jmoiron/sqlx's In().
+func anyExpandable(args []interface{}) bool {
+	for _, arg := range args {
+		if _, ok := expandable(arg); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mapQueries returns a copy of DotSqlx with fn applied to all queries,
+// including dialect-specific variants.
+func (d DotSqlx) mapQueries(fn func(query string) (string, error)) (*DotSqlx, error) {
+	var (
+		qm  = d.QueryMap()
+		res = make(map[string]string, len(qm))
+		vv  map[string]map[string]string
+	)
+
+	for _, name := range d.Names() {
+		if query, ok := qm[name]; ok {
+			query, err := fn(query)
+			if err != nil {
+				return nil, &QueryError{Name: name, Err: err}
+			}
+
+			res[name] = query
+		}
+
+		if _, ok := d.variants[name]; !ok {
+			continue
+		}
+
+		v := make(map[string]string, len(d.variants[name]))
+		for dialect, query := range d.variants[name] {
+			query, err := fn(query)
+			if err != nil {
+				return nil, &QueryError{Name: name, Err: err}
+			}
+
+			v[dialect] = query
+		}
+
+		if vv == nil {
+			vv = make(map[string]map[string]string, len(d.variants))
+		}
+
+		vv[name] = v
+	}
+
+	d.queries = res
+	d.variants = vv
+	return &d, nil
+}
+
+// unnumber converts sequentially numbered `$1` or `@p1` bindvars to `?`.
+func unnumber(bindType int, query string) (string, error) {
+	prefix := "$"
+	if bindType == sqlx.AT {
+		prefix = "@p"
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+
+	tt, _ := lex(query)
+	for _, t := range tt {
+		if t.kind != tokenCode {
+			b.WriteString(t.text)
+			continue
+		}
+
+		s := t.text
+		for {
+			i := strings.Index(s, prefix)
+			if i < 0 {
+				b.WriteString(s)
+				break
+			}
+
+			j := i + len(prefix)
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+
+			b.WriteString(s[:i])
+
+			if j == i+len(prefix) {
+				b.WriteString(s[i:j])
+				s = s[j:]
+				continue
+			}
+
+			n++
+			if num, _ := strconv.Atoi(s[i+len(prefix) : j]); num != n {
+				return "", fmt.Errorf("dotsqlx: bindvar %s is not numbered sequentially", s[i:j])
+			}
+
+			b.WriteByte('?')
+			s = s[j:]
+		}
+	}
+
+	return b.String(), nil
+}
+
+// renumber converts `?` bindvars to bindType.
+func renumber(bindType int, query string) string {
+	if bindType == sqlx.QUESTION || bindType == sqlx.UNKNOWN {
+		return query
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+
+	tt, _ := lex(query)
+	for _, t := range tt {
+		if t.kind != tokenCode {
+			b.WriteString(t.text)
+			continue
+		}
+
+		for i := 0; i < len(t.text); i++ {
+			if t.text[i] != '?' {
+				b.WriteByte(t.text[i])
+				continue
+			}
+
+			n++
+			switch bindType {
+			case sqlx.DOLLAR:
+				b.WriteByte('$')
+			case sqlx.NAMED:
+				b.WriteString(":arg")
+			case sqlx.AT:
+				b.WriteString("@p")
+			}
+
+			b.WriteString(strconv.Itoa(n))
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,243 @@
+package dotsqlx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindType(t *testing.T) {
+	cc := map[string]struct {
+		Query    string
+		BindType int
+		Err      error
+	}{
+		"no bindvars": {
+			Query:    "SELECT '?' FROM t",
+			BindType: sqlx.UNKNOWN,
+		},
+		"question": {
+			Query:    "SELECT * FROM t WHERE a = ?",
+			BindType: sqlx.QUESTION,
+		},
+		"dollar": {
+			Query:    "SELECT * FROM t WHERE a = $1",
+			BindType: sqlx.DOLLAR,
+		},
+		"mixed": {
+			Query:    "SELECT * FROM t WHERE a = ? AND b = :b",
+			BindType: sqlx.UNKNOWN,
+			Err:      ErrMixedBindTypes,
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			bt, err := BindType(c.Query)
+			assert.Equal(t, c.Err, err)
+			assert.Equal(t, c.BindType, bt)
+		})
+	}
+}
+
+func TestRebindQuery(t *testing.T) {
+	cc := map[string]struct {
+		BindType int
+		Query    string
+		Result   string
+		Err      bool
+	}{
+		"question to dollar": {
+			BindType: sqlx.DOLLAR,
+			Query:    "SELECT '?', \"?\" FROM t -- ?\nWHERE a = ? AND b = ?",
+			Result:   "SELECT '?', \"?\" FROM t -- ?\nWHERE a = $1 AND b = $2",
+		},
+		"question to at": {
+			BindType: sqlx.AT,
+			Query:    "SELECT * FROM t WHERE a = ? AND b = ?",
+			Result:   "SELECT * FROM t WHERE a = @p1 AND b = @p2",
+		},
+		"question to named": {
+			BindType: sqlx.NAMED,
+			Query:    "SELECT * FROM t WHERE a = ?",
+			Result:   "SELECT * FROM t WHERE a = :arg1",
+		},
+		"dollar to question": {
+			BindType: sqlx.QUESTION,
+			Query:    "SELECT $$ $1 $$, a::int FROM t WHERE a = $1 AND b = $2",
+			Result:   "SELECT $$ $1 $$, a::int FROM t WHERE a = ? AND b = ?",
+		},
+		"at to dollar": {
+			BindType: sqlx.DOLLAR,
+			Query:    "SELECT * FROM t WHERE a = @p1 AND b = @p2",
+			Result:   "SELECT * FROM t WHERE a = $1 AND b = $2",
+		},
+		"same type": {
+			BindType: sqlx.DOLLAR,
+			Query:    "SELECT * FROM t WHERE a = $2 AND b = $1",
+			Result:   "SELECT * FROM t WHERE a = $2 AND b = $1",
+		},
+		"named": {
+			BindType: sqlx.DOLLAR,
+			Query:    "SELECT * FROM t WHERE a = :a",
+			Result:   "SELECT * FROM t WHERE a = :a",
+		},
+		"dollar not sequential": {
+			BindType: sqlx.QUESTION,
+			Query:    "SELECT * FROM t WHERE a = $1 OR b = $1",
+			Err:      true,
+		},
+		"at not numbered": {
+			BindType: sqlx.QUESTION,
+			Query:    "SELECT * FROM t WHERE a = @a",
+			Err:      true,
+		},
+		"mixed": {
+			BindType: sqlx.DOLLAR,
+			Query:    "SELECT * FROM t WHERE a = ? AND b = $1",
+			Err:      true,
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := RebindQuery(c.BindType, c.Query)
+			if c.Err {
+				assert.NotNil(t, err)
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, c.Result, res)
+		})
+	}
+}
+
+func TestRebindAll(t *testing.T) {
+	dot := newDot(t, `
+-- name: select_question
+SELECT * FROM t WHERE a = ?
+
+-- name: select_named
+SELECT * FROM t WHERE a = :a
+`)
+
+	res, err := dot.RebindAll(sqlx.DOLLAR)
+	require.Nil(t, err)
+
+	q, err := res.Raw("select_question")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1", q)
+
+	q, err = res.Raw("select_named")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE a = :a", q)
+
+	_, err = res.Raw("missing")
+	assert.NotNil(t, err)
+
+	q, err = dot.Raw("select_question")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE a = ?", q)
+
+	dot = newDot(t, `
+-- name: select_a
+SELECT * FROM t WHERE a = ?
+
+-- name: select_mixed
+SELECT * FROM t WHERE a = ? AND b = :b
+`)
+
+	_, err = dot.RebindAll(sqlx.DOLLAR)
+
+	qerr, ok := err.(*QueryError)
+	require.True(t, ok)
+	assert.Equal(t, "select_mixed", qerr.Name)
+	assert.Equal(t, ErrMixedBindTypes, qerr.Err)
+}
+
+func TestRebindAllIn(t *testing.T) {
+	dot, err := newDot(t, chunkQueries).RebindAll(sqlx.DOLLAR)
+	require.Nil(t, err)
+
+	db := newDB(t, 10)
+	defer db.Close()
+
+	// In
+	query, args, err := dot.In("select_in", 1, []int{2, 3})
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT nr FROM numbers WHERE nr > $1 AND nr IN ($2, $3) ORDER BY nr", query)
+	assert.Equal(t, []interface{}{1, 2, 3}, args)
+
+	query, args, err = dot.MustLookup("select_in").In(1, []int{2, 3})
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT nr FROM numbers WHERE nr > $1 AND nr IN ($2, $3) ORDER BY nr", query)
+	assert.Equal(t, []interface{}{1, 2, 3}, args)
+
+	// InChunks
+	cc, err := dot.InChunks(2, "select_in", 1, []int{2, 3})
+	require.Nil(t, err)
+	require.Len(t, cc, 2)
+	assert.Equal(t, "SELECT nr FROM numbers WHERE nr > $1 AND nr IN ($2) ORDER BY nr", cc[1].Query)
+	assert.Equal(t, []interface{}{1, 3}, cc[1].Args)
+
+	// SelectInChunks
+	var nrs []int
+	err = dot.SelectInChunks(context.Background(), db, ChunkOptions{MaxParams: 2}, &nrs, "select_in", 1, []int{2, 3, 4})
+	require.Nil(t, err)
+	assert.Equal(t, []int{2, 3, 4}, nrs)
+
+	// ExecInChunks
+	n, err := dot.ExecInChunks(context.Background(), db, ChunkOptions{MaxParams: 2}, "delete_in", []int{2, 3, 4})
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+
+	// `@p1` bindvars
+	dot, err = dot.RebindAll(sqlx.AT)
+	require.Nil(t, err)
+
+	query, _, err = dot.In("select_in", 1, []int{2, 3})
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT nr FROM numbers WHERE nr > @p1 AND nr IN (@p2, @p3) ORDER BY nr", query)
+}
+
+func TestInWithoutSlices(t *testing.T) {
+	dot := newDot(t, `
+-- name: select_reused
+SELECT * FROM t WHERE a = $1 OR b = $1
+
+-- name: select_json
+SELECT * FROM t WHERE data ? 'k' AND id = $1
+`)
+
+	// reused bindvar
+	query, args, err := dot.In("select_reused", 1)
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1 OR b = $1", query)
+	assert.Equal(t, []interface{}{1}, args)
+
+	cc, err := dot.InChunks(10, "select_reused", 1)
+	require.Nil(t, err)
+	assert.Equal(t, []Chunk{{Query: "SELECT * FROM t WHERE a = $1 OR b = $1", Args: []interface{}{1}}}, cc)
+
+	// `?` operator
+	query, args, err = dot.MustLookup("select_json").In(1)
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE data ? 'k' AND id = $1", query)
+	assert.Equal(t, []interface{}{1}, args)
+
+	// bindvars checked if a slice has to be expanded
+	_, _, err = dot.In("select_reused", []int{1, 2})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,270 @@
+package dotsqlx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// annotationKeyRe matches a valid annotation key.
+var annotationKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// ParseYAML reads named query definitions from a YAML catalog that maps
+// query names to either their SQL or an object holding the SQL under the
+// `sql` key and metadata under any other key:
+//
+//	select_users:
+//	  sql: SELECT * FROM users
+//	  description: Lists all users
+//	  tags: [admin, reports]
+//
+// Metadata is converted into leading `-- key: value` annotations, in the
+// document order; list values produce one annotation per element. As with
+// dotsql files, Validate reports metadata keys that are not known
+// annotations, e.g. ones not registered with RegisterAnnotation. The file
+// name is only used for source locations.
+func ParseYAML(r io.Reader, file string) ([]Definition, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("dotsqlx: %s: %v", file, err)
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("dotsqlx: %s:%d: catalog must be a mapping", file, root.Line)
+	}
+
+	var dd []Definition
+	for i := 0; i < len(root.Content); i += 2 {
+		k, v := root.Content[i], root.Content[i+1]
+		def := Definition{Name: k.Value, File: file, Line: k.Line}
+
+		var (
+			query string
+			meta  Annotations
+			err   error
+		)
+
+		switch v.Kind {
+		case yaml.ScalarNode:
+			query = v.Value
+		case yaml.MappingNode:
+			for j := 0; j < len(v.Content); j += 2 {
+				mk, mv := v.Content[j].Value, v.Content[j+1]
+				if mk == "sql" {
+					query = mv.Value
+					continue
+				}
+
+				var vv []string
+				switch mv.Kind {
+				case yaml.ScalarNode:
+					vv = []string{mv.Value}
+				case yaml.SequenceNode:
+					for _, n := range mv.Content {
+						if n.Kind != yaml.ScalarNode {
+							return nil, catalogError(def, "invalid %q value", mk)
+						}
+
+						vv = append(vv, n.Value)
+					}
+				default:
+					return nil, catalogError(def, "invalid %q value", mk)
+				}
+
+				for _, val := range vv {
+					meta = append(meta, Annotation{Key: mk, Value: val})
+				}
+			}
+		default:
+			return nil, catalogError(def, "invalid query")
+		}
+
+		if def, err = catalogDefinition(def, query, meta); err != nil {
+			return nil, err
+		}
+
+		dd = append(dd, def)
+	}
+
+	return dd, nil
+}
+
+// ParseJSON reads named query definitions from a JSON catalog, which has
+// the same structure as the one read by ParseYAML. The file name is only
+// used for source locations.
+func ParseJSON(r io.Reader, file string) ([]Definition, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	ff, err := jsonFields(data)
+	if err != nil {
+		return nil, fmt.Errorf("dotsqlx: %s: %v", file, err)
+	}
+
+	var dd []Definition
+	for _, f := range ff {
+		def := Definition{Name: f.key, File: file, Line: 1 + bytes.Count(data[:f.offset], []byte("\n"))}
+
+		var (
+			query string
+			meta  Annotations
+		)
+
+		if err := json.Unmarshal(f.value, &query); err != nil {
+			mff, err := jsonFields(f.value)
+			if err != nil {
+				return nil, catalogError(def, "invalid query")
+			}
+
+			for _, mf := range mff {
+				if mf.key == "sql" {
+					if err := json.Unmarshal(mf.value, &query); err != nil {
+						return nil, catalogError(def, "invalid %q value", mf.key)
+					}
+
+					continue
+				}
+
+				vv, err := jsonValues(mf.value)
+				if err != nil {
+					return nil, catalogError(def, "invalid %q value", mf.key)
+				}
+
+				for _, val := range vv {
+					meta = append(meta, Annotation{Key: mf.key, Value: val})
+				}
+			}
+		}
+
+		if def, err = catalogDefinition(def, query, meta); err != nil {
+			return nil, err
+		}
+
+		dd = append(dd, def)
+	}
+
+	return dd, nil
+}
+
+// jsonField is a member of a JSON object.
+type jsonField struct {
+	key    string
+	value  json.RawMessage
+	offset int64
+}
+
+// jsonFields returns the members of a JSON object in their order.
+func jsonFields(data []byte) ([]jsonField, error) {
+	r := bytes.NewReader(data)
+	dec := json.NewDecoder(r)
+
+	if t, err := dec.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('{') {
+		return nil, errors.New("catalog must be an object")
+	}
+
+	var ff []jsonField
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		// the offset right after the key is the size of the read data less
+		// the size of the data the decoder has buffered
+		buffered, err := io.Copy(ioutil.Discard, dec.Buffered())
+		if err != nil {
+			return nil, err
+		}
+
+		f := jsonField{key: t.(string), offset: int64(len(data)-r.Len()) - buffered}
+		if err := dec.Decode(&f.value); err != nil {
+			return nil, err
+		}
+
+		ff = append(ff, f)
+	}
+
+	return ff, nil
+}
+
+// jsonValues converts a JSON scalar or an array of scalars into strings.
+func jsonValues(data json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	vv, ok := v.([]interface{})
+	if !ok {
+		vv = []interface{}{v}
+	}
+
+	res := make([]string, len(vv))
+	for i, v := range vv {
+		switch v.(type) {
+		case string, json.Number, bool:
+			res[i] = fmt.Sprint(v)
+		default:
+			return nil, errors.New("invalid value")
+		}
+	}
+
+	return res, nil
+}
+
+// catalogDefinition completes the definition of a catalog query. Its SQL is
+// built the way dotsql builds query bodies, preceded by the metadata
+// annotations.
+func catalogDefinition(def Definition, query string, meta Annotations) (Definition, error) {
+	var lines []string
+
+	for _, a := range meta {
+		if !annotationKeyRe.MatchString(a.Key) {
+			return Definition{}, catalogError(def, "invalid metadata key %q", a.Key)
+		}
+
+		if strings.ContainsAny(a.Value, "\r\n") {
+			return Definition{}, catalogError(def, "multi-line %q value", a.Key)
+		}
+
+		lines = append(lines, strings.TrimRight("-- "+a.Key+": "+a.Value, " "))
+	}
+
+	for _, l := range strings.Split(query, "\n") {
+		if l = strings.Trim(l, " \t\r"); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	def.SQL = strings.Join(lines, "\n")
+	def.Annotations = ParseAnnotations(def.SQL)
+
+	return def, nil
+}
+
+func catalogError(def Definition, format string, args ...interface{}) error {
+	return fmt.Errorf("dotsqlx: %s: %s: %s", def.Location(), def.Name, fmt.Sprintf(format, args...))
+}
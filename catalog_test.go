@@ -0,0 +1,179 @@
+package dotsqlx
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCatalog(t *testing.T) {
+	cc := map[string]struct {
+		Parse   func(r io.Reader, file string) ([]Definition, error)
+		Catalog string
+		Defs    []Definition
+		Err     string
+	}{
+		"empty yaml": {
+			Parse: ParseYAML,
+		},
+		"invalid yaml": {
+			Parse:   ParseYAML,
+			Catalog: "a: [",
+			Err:     "dotsqlx: c: yaml:",
+		},
+		"yaml list": {
+			Parse:   ParseYAML,
+			Catalog: "- a",
+			Err:     "dotsqlx: c:1: catalog must be a mapping",
+		},
+		"yaml invalid query": {
+			Parse:   ParseYAML,
+			Catalog: "\na: [SELECT 1]",
+			Err:     "dotsqlx: c:2: a: invalid query",
+		},
+		"yaml invalid metadata value": {
+			Parse:   ParseYAML,
+			Catalog: "a:\n  sql: SELECT 1\n  owner: {name: x}",
+			Err:     `dotsqlx: c:1: a: invalid "owner" value`,
+		},
+		"yaml invalid metadata key": {
+			Parse:   ParseYAML,
+			Catalog: "a:\n  sql: SELECT 1\n  Owner: x",
+			Err:     `dotsqlx: c:1: a: invalid metadata key "Owner"`,
+		},
+		"yaml multi-line metadata": {
+			Parse:   ParseYAML,
+			Catalog: "a:\n  sql: SELECT 1\n  description: \"a\\nb\"",
+			Err:     `dotsqlx: c:1: a: multi-line "description" value`,
+		},
+		"yaml": {
+			Parse:   ParseYAML,
+			Catalog: "a: SELECT 1\nb:\n  tags: [x, y]\n  sql: |\n    SELECT 2\n\n     FROM t",
+			Defs: []Definition{
+				{Name: "a", SQL: "SELECT 1", File: "c", Line: 1},
+				{
+					Name:        "b",
+					SQL:         "-- tags: x\n-- tags: y\nSELECT 2\nFROM t",
+					File:        "c",
+					Line:        2,
+					Annotations: Annotations{{Key: "tags", Value: "x"}, {Key: "tags", Value: "y"}},
+				},
+			},
+		},
+		"empty json": {
+			Parse: ParseJSON,
+		},
+		"invalid json": {
+			Parse:   ParseJSON,
+			Catalog: "{",
+			Err:     "dotsqlx: c: unexpected end of JSON input",
+		},
+		"json array": {
+			Parse:   ParseJSON,
+			Catalog: "[]",
+			Err:     "dotsqlx: c: catalog must be an object",
+		},
+		"json invalid query": {
+			Parse:   ParseJSON,
+			Catalog: "{\n\"a\": 1}",
+			Err:     "dotsqlx: c:2: a: invalid query",
+		},
+		"json invalid sql": {
+			Parse:   ParseJSON,
+			Catalog: `{"a": {"sql": 1}}`,
+			Err:     `dotsqlx: c:1: a: invalid "sql" value`,
+		},
+		"json invalid metadata value": {
+			Parse:   ParseJSON,
+			Catalog: `{"a": {"sql": "SELECT 1", "owner": {}}}`,
+			Err:     `dotsqlx: c:1: a: invalid "owner" value`,
+		},
+		"json": {
+			Parse:   ParseJSON,
+			Catalog: "{\"a\": \"SELECT 1\",\n\"b\": {\"cached\": true, \"sql\": \"SELECT 2\"}}",
+			Defs: []Definition{
+				{Name: "a", SQL: "SELECT 1", File: "c", Line: 1},
+				{
+					Name:        "b",
+					SQL:         "-- cached: true\nSELECT 2",
+					File:        "c",
+					Line:        2,
+					Annotations: Annotations{{Key: "cached", Value: "true"}},
+				},
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			dd, err := c.Parse(strings.NewReader(c.Catalog), "c")
+			if c.Err != "" {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), c.Err)
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, c.Defs, dd)
+		})
+	}
+}
+
+func TestLoadCatalogs(t *testing.T) {
+	yd, err := LoadDefinitions("testdata/catalog.yaml")
+	require.Nil(t, err)
+
+	jd, err := LoadDefinitions("testdata/catalog.json")
+	require.Nil(t, err)
+
+	require.Len(t, yd, 2)
+	require.Len(t, jd, 2)
+
+	for i := range yd {
+		assert.Equal(t, yd[i].Name, jd[i].Name)
+		assert.Equal(t, yd[i].SQL, jd[i].SQL)
+	}
+
+	assert.Equal(t, "testdata/catalog.yaml:3", yd[1].Location())
+	assert.Equal(t, "testdata/catalog.json:3", jd[1].Location())
+	assert.Equal(t, []string{"reports", "admin"}, yd[1].Annotations.Tags())
+	assert.Equal(t, "5s", yd[1].Annotations.Get("timeout"))
+	assert.Empty(t, Validate(yd))
+	assert.Empty(t, Validate(jd))
+
+	// unknown metadata keys reported as in dotsql files
+	ud, err := ParseYAML(strings.NewReader("a:\n  sql: SELECT 1\n  retries: 3"), "c")
+	require.Nil(t, err)
+
+	ii := Validate(ud)
+	require.Len(t, ii, 1)
+	assert.Equal(t, `c:1: a: unknown annotation "retries"`, ii[0].String())
+
+	r := NewRegistry()
+	require.Nil(t, r.AddDefinitions(yd))
+	assert.EqualError(t, r.AddDefinitions(jd), "dotsqlx: testdata/catalog.json:2: count_numbers: already exists")
+
+	dd, err := LoadDefinitions("testdata/includes.sql")
+	require.Nil(t, err)
+	require.Nil(t, NewRegistry().AddDefinitions(append(dd, yd...)))
+
+	dot, err := Load("testdata/includes.sql", "testdata/catalog.yaml")
+	require.Nil(t, err)
+
+	db := newDB(t, 5)
+
+	var nn []int
+	require.Nil(t, dot.Select(db, &nn, "select_numbers", 3))
+	assert.Equal(t, []int{3, 4}, nn)
+
+	aa, err := dot.Annotations("select_numbers")
+	require.Nil(t, err)
+	assert.Equal(t, "Lists numbers", aa.Get("description"))
+}
@@ -0,0 +1,327 @@
+package dotsqlx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultMaxParams is the bound parameters limit used when the driver is
+// unknown.
+const DefaultMaxParams = 999
+
+// ErrTooManyParams is returned when a query cannot be split into chunks
+// that respect the bound parameters limit.
+var ErrTooManyParams = errors.New("dotsqlx: too many bound parameters")
+
+// driverMaxParams holds bound parameters limits of well known drivers.
+var driverMaxParams = map[string]int{
+	"sqlite3":          999,
+	"sqlite":           999,
+	"postgres":         65535,
+	"pgx":              65535,
+	"cloudsqlpostgres": 65535,
+	"mysql":            65535,
+	"sqlserver":        2100,
+	"mssql":            2100,
+}
+
+// MaxParams returns the bound parameters limit of the driver. If the driver
+// is unknown, DefaultMaxParams is returned.
+func MaxParams(driverName string) int {
+	if n, ok := driverMaxParams[driverName]; ok {
+		return n
+	}
+
+	return DefaultMaxParams
+}
+
+// ChunkOptions holds the options of chunked IN expansions.
+type ChunkOptions struct {
+	// MaxParams is the maximum number of bound parameters per statement.
+	// If zero, the limit of the executor's driver is used.
+	MaxParams int
+
+	// Parallel is the number of chunks executed concurrently. Chunks are
+	// executed one after another if it is lower than 2 or if the executor
+	// is a transaction, whose single connection cannot run statements
+	// concurrently.
+	Parallel int
+}
+
+// Chunk is a single expanded query along with its arguments.
+type Chunk struct {
+	Query string
+	Args  []interface{}
+}
+
+// InChunks is a wrapper for jmoiron/sqlx's In(), using dotsql named query.
+// The largest slice argument is split so that every returned chunk holds at
+// most maxParams bound parameters. Chunks keep the bindvar style of the
+// query.
+func (d DotSqlx) InChunks(maxParams int, name string, args ...interface{}) ([]Chunk, error) {
+	query, err := d.Raw(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return inChunks(query, maxParams, args)
+}
+
+// InChunksFor is like InChunks but uses the variant of dotsql named query
+// for the dialect of the driver, falling back to the dialect-neutral one.
+func (d DotSqlx) InChunksFor(driverName string, maxParams int, name string, args ...interface{}) ([]Chunk, error) {
+	query, err := d.RawFor(driverName, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return inChunks(query, maxParams, args)
+}
+
+// SelectInChunks expands slice arguments of dotsql named query into chunks
+// that respect the bound parameters limit, executes jmoiron/sqlx's
+// SelectContext() per chunk and appends all results to dest, which must be
+// a pointer to a slice. If dbx is a transaction, all chunks are executed
+// inside it, one after another.
+func (d DotSqlx) SelectInChunks(ctx context.Context, dbx sqlx.ExtContext, opts ChunkOptions, dest interface{}, name string, args ...interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return errors.New("dotsqlx: dest must be a pointer to a slice")
+	}
+
+	query, err := d.raw(dbx, name)
+	if err != nil {
+		return err
+	}
+
+	cc, err := inChunks(query, opts.maxParams(dbx), args)
+	if err != nil {
+		return err
+	}
+
+	res := make([]reflect.Value, len(cc))
+	err = runChunks(ctx, opts.parallel(dbx), len(cc), func(ctx context.Context, i int) error {
+		part := reflect.New(v.Elem().Type())
+		if err := sqlx.SelectContext(ctx, dbx, part.Interface(), dbx.Rebind(cc[i].Query), cc[i].Args...); err != nil {
+			return err
+		}
+
+		res[i] = part.Elem()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, part := range res {
+		v.Elem().Set(reflect.AppendSlice(v.Elem(), part))
+	}
+
+	return nil
+}
+
+// ExecInChunks expands slice arguments of dotsql named query into chunks
+// that respect the bound parameters limit, executes each chunk and returns
+// the total number of affected rows. If dbx is a transaction, all chunks
+// are executed inside it, one after another. Otherwise the chunks executed before an error
+// are not rolled back, so the rows they affected are returned along with
+// the error.
+func (d DotSqlx) ExecInChunks(ctx context.Context, dbx sqlx.ExtContext, opts ChunkOptions, name string, args ...interface{}) (int64, error) {
+	query, err := d.raw(dbx, name)
+	if err != nil {
+		return 0, err
+	}
+
+	cc, err := inChunks(query, opts.maxParams(dbx), args)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		mu    sync.Mutex
+		total int64
+	)
+
+	err = runChunks(ctx, opts.parallel(dbx), len(cc), func(ctx context.Context, i int) error {
+		res, err := dbx.ExecContext(ctx, dbx.Rebind(cc[i].Query), cc[i].Args...)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		total += n
+		mu.Unlock()
+		return nil
+	})
+
+	return total, err
+}
+
+// maxParams returns the configured bound parameters limit or the limit of
+// the executor's driver.
+func (o ChunkOptions) maxParams(dbx sqlx.ExtContext) int {
+	if o.MaxParams > 0 {
+		return o.MaxParams
+	}
+
+	return MaxParams(dbx.DriverName())
+}
+
+// txer is implemented by transactions, e.g. *sqlx.Tx.
+type txer interface {
+	Commit() error
+	Rollback() error
+}
+
+// parallel returns the number of chunks that may be executed concurrently
+// by the executor.
+func (o ChunkOptions) parallel(dbx sqlx.ExtContext) int {
+	if _, ok := dbx.(txer); ok {
+		return 1
+	}
+
+	return o.Parallel
+}
+
+// inChunks splits the largest slice argument into parts so that each
+// expanded query holds at most maxParams bound parameters.
+func inChunks(query string, maxParams int, args []interface{}) ([]Chunk, error) {
+	var (
+		total   int
+		largest = -1
+		sizes   = make([]int, len(args))
+	)
+
+	for i, arg := range args {
+		sizes[i] = 1
+
+		if v, ok := expandable(arg); ok {
+			sizes[i] = v.Len()
+			if largest < 0 || sizes[i] > sizes[largest] {
+				largest = i
+			}
+		}
+
+		total += sizes[i]
+	}
+
+	if total <= maxParams || largest < 0 {
+		q, a, err := in(query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Chunk{{Query: q, Args: a}}, nil
+	}
+
+	size := maxParams - (total - sizes[largest])
+	if size < 1 {
+		return nil, ErrTooManyParams
+	}
+
+	v, _ := expandable(args[largest])
+
+	var cc []Chunk
+	for start := 0; start < v.Len(); start += size {
+		end := start + size
+		if end > v.Len() {
+			end = v.Len()
+		}
+
+		part := make([]interface{}, len(args))
+		copy(part, args)
+		part[largest] = v.Slice(start, end).Interface()
+
+		q, a, err := in(query, part...)
+		if err != nil {
+			return nil, err
+		}
+
+		cc = append(cc, Chunk{Query: q, Args: a})
+	}
+
+	return cc, nil
+}
+
+// expandable returns the slice value of the argument if it would be
+// expanded by jmoiron/sqlx's In().
+func expandable(arg interface{}) (reflect.Value, bool) {
+	if a, ok := arg.(driver.Valuer); ok {
+		var err error
+		if arg, err = a.Value(); err != nil {
+			return reflect.Value{}, false
+		}
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	if v.Kind() != reflect.Slice || v.Type() == reflect.TypeOf([]byte{}) {
+		return reflect.Value{}, false
+	}
+
+	return v, true
+}
+
+// runChunks calls fn for every chunk index, running at most parallel calls
+// concurrently. The first encountered error cancels the remaining calls.
+func runChunks(ctx context.Context, parallel, n int, fn func(ctx context.Context, i int) error) error {
+	if parallel < 2 {
+		for i := 0; i < n; i++ {
+			if err := fn(ctx, i); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		ferr error
+		sem  = make(chan struct{}, parallel)
+	)
+
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					ferr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if ferr == nil {
+		ferr = ctx.Err()
+	}
+
+	return ferr
+}
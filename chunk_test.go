@@ -0,0 +1,173 @@
+package dotsqlx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const chunkQueries = `
+-- name: select_in
+SELECT nr FROM numbers WHERE nr > ? AND nr IN (?) ORDER BY nr
+
+-- name: delete_in
+DELETE FROM numbers WHERE nr IN (?)
+
+-- name: invalid_in
+SELECT nr FROM missing WHERE nr IN (?)`
+
+func TestMaxParams(t *testing.T) {
+	assert.Equal(t, 999, MaxParams("sqlite3"))
+	assert.Equal(t, 65535, MaxParams("postgres"))
+	assert.Equal(t, DefaultMaxParams, MaxParams("unknown"))
+}
+
+func TestInChunks(t *testing.T) {
+	dot := newDot(t, chunkQueries)
+
+	// query not found
+	cc, err := dot.InChunks(10, "select_in123", 1, []int{1, 2})
+	assert.Nil(t, cc)
+	assert.NotNil(t, err)
+
+	// single chunk
+	cc, err = dot.InChunks(10, "select_in", 1, []int{1, 2, 3})
+	require.Nil(t, err)
+	require.Len(t, cc, 1)
+	assert.Equal(t, "SELECT nr FROM numbers WHERE nr > ? AND nr IN (?, ?, ?) ORDER BY nr", cc[0].Query)
+	assert.Equal(t, []interface{}{1, 1, 2, 3}, cc[0].Args)
+
+	// multiple chunks
+	cc, err = dot.InChunks(3, "select_in", 1, []int{1, 2, 3, 4, 5})
+	require.Nil(t, err)
+	require.Len(t, cc, 3)
+	assert.Equal(t, []interface{}{1, 1, 2}, cc[0].Args)
+	assert.Equal(t, []interface{}{1, 3, 4}, cc[1].Args)
+	assert.Equal(t, []interface{}{1, 5}, cc[2].Args)
+
+	// limit too low
+	cc, err = dot.InChunks(1, "select_in", 1, []int{1, 2})
+	assert.Nil(t, cc)
+	assert.Equal(t, ErrTooManyParams, err)
+}
+
+func TestSelectInChunks(t *testing.T) {
+	dot := newDot(t, chunkQueries)
+	db := newDB(t, 2500)
+	defer db.Close()
+
+	ids := make([]int, 2500)
+	for i := range ids {
+		ids[i] = i
+	}
+
+	// invalid dest
+	var nrs []int
+	err := dot.SelectInChunks(context.Background(), db, ChunkOptions{}, nrs, "select_in", -1, ids)
+	assert.NotNil(t, err)
+
+	// query not found
+	err = dot.SelectInChunks(context.Background(), db, ChunkOptions{}, &nrs, "select_in123", -1, ids)
+	assert.NotNil(t, err)
+	assert.Empty(t, nrs)
+
+	// error returned by db
+	err = dot.SelectInChunks(context.Background(), db, ChunkOptions{}, &nrs, "invalid_in", ids)
+	assert.NotNil(t, err)
+
+	// successful call with driver limit
+	err = dot.SelectInChunks(context.Background(), db, ChunkOptions{}, &nrs, "select_in", -1, ids)
+	require.Nil(t, err)
+	assert.Equal(t, ids, nrs)
+
+	// successful parallel call
+	for _, p := range []int{2, 10} {
+		t.Run(fmt.Sprintf("parallel %d", p), func(t *testing.T) {
+			var nrs []int
+			err := dot.SelectInChunks(context.Background(), db, ChunkOptions{MaxParams: 100, Parallel: p}, &nrs, "select_in", 9, ids)
+			require.Nil(t, err)
+			assert.Equal(t, ids[10:], nrs)
+		})
+	}
+}
+
+func TestExecInChunks(t *testing.T) {
+	dot := newDot(t, chunkQueries)
+	db := newDB(t, 2500)
+	defer db.Close()
+
+	ids := make([]int, 2000)
+	for i := range ids {
+		ids[i] = i
+	}
+
+	// query not found
+	n, err := dot.ExecInChunks(context.Background(), db, ChunkOptions{}, "delete_in123", ids)
+	assert.Zero(t, n)
+	assert.NotNil(t, err)
+
+	// error returned by db
+	n, err = dot.ExecInChunks(context.Background(), db, ChunkOptions{}, "invalid_in", ids)
+	assert.Zero(t, n)
+	assert.NotNil(t, err)
+
+	// successful call inside a transaction
+	tx := db.MustBegin()
+	n, err = dot.ExecInChunks(context.Background(), tx, ChunkOptions{MaxParams: 300}, "delete_in", ids[:1000])
+	require.Nil(t, err)
+	assert.Equal(t, int64(1000), n)
+	require.Nil(t, tx.Rollback())
+
+	// parallel call inside a transaction executed sequentially
+	ctr := &concurrencyTx{Tx: db.MustBegin()}
+	n, err = dot.ExecInChunks(context.Background(), ctr, ChunkOptions{MaxParams: 100, Parallel: 4}, "delete_in", ids[:1000])
+	require.Nil(t, err)
+	assert.Equal(t, int64(1000), n)
+	assert.Equal(t, int32(1), ctr.max)
+	require.Nil(t, ctr.Rollback())
+
+	// successful parallel call
+	n, err = dot.ExecInChunks(context.Background(), db, ChunkOptions{Parallel: 4}, "delete_in", ids)
+	require.Nil(t, err)
+	assert.Equal(t, int64(2000), n)
+
+	// error returned by db after some chunks
+	db = newDB(t, 2000)
+	defer db.Close()
+
+	db.MustExec("CREATE TRIGGER abort_delete BEFORE DELETE ON numbers WHEN old.nr = 1500 BEGIN SELECT RAISE(ABORT, 'aborted'); END")
+
+	n, err = dot.ExecInChunks(context.Background(), db, ChunkOptions{MaxParams: 500}, "delete_in", ids)
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(1500), n)
+}
+
+// concurrencyTx records the highest number of concurrent executions.
+type concurrencyTx struct {
+	*sqlx.Tx
+
+	cur, max int32
+}
+
+func (c *concurrencyTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	n := atomic.AddInt32(&c.cur, 1)
+	defer atomic.AddInt32(&c.cur, -1)
+
+	for {
+		m := atomic.LoadInt32(&c.max)
+		if n <= m || atomic.CompareAndSwapInt32(&c.max, m, n) {
+			break
+		}
+	}
+
+	time.Sleep(time.Millisecond)
+
+	return c.Tx.ExecContext(ctx, query, args...)
+}
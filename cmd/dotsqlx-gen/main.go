@@ -0,0 +1,75 @@
+// Command dotsqlx-gen generates typed Go methods from annotated dotsql
+// files. It is meant to be used with go:generate:
+//
+//	//go:generate dotsqlx-gen -pkg store -o queries_gen.go queries.sql
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/swithek/dotsqlx"
+	"github.com/swithek/dotsqlx/gen"
+)
+
+func main() {
+	out := flag.String("o", "", "output file (defaults to stdout)")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "name of the generated package")
+	typ := flag.String("type", "Queries", "name of the generated queries struct")
+	iface := flag.String("interface", "Querier", "name of the generated queries interface")
+	fake := flag.String("fake", "", "name of the generated fake implementation (defaults to Fake<type>)")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: dotsqlx-gen [flags] file.sql...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*out, gen.Config{Package: *pkg, Type: *typ, Interface: *iface, Fake: *fake}, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "dotsqlx-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(out string, cfg gen.Config, files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no input files")
+	}
+
+	if cfg.Package == "" {
+		return fmt.Errorf("package name not set")
+	}
+
+	dot, err := dotsqlx.Load(files...)
+	if err != nil {
+		return err
+	}
+
+	// Queries that only have dialect-specific variants take their
+	// annotations from the first variant.
+	qm := make(map[string]string)
+	for _, d := range dot.Definitions() {
+		if _, ok := qm[d.Name]; !ok {
+			qm[d.Name] = d.SQL
+		}
+	}
+
+	qq, err := gen.Parse(qm)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err = gen.Generate(&buf, cfg, qq); err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+
+	return ioutil.WriteFile(out, buf.Bytes(), 0644)
+}
@@ -0,0 +1,76 @@
+// Command dotsqlx-unused lists the dotsql named queries that are never
+// referenced by constant query names passed to dotsqlx methods in the
+// given Go packages. It exits with status 1 if any unused query is found.
+//
+//	dotsqlx-unused -queries 'sql/*.sql' ./...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/swithek/dotsqlx"
+	"github.com/swithek/dotsqlx/querycheck"
+)
+
+func main() {
+	queries := flag.String("queries", "", "comma separated list of .sql files or glob patterns")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: dotsqlx-unused -queries patterns [packages]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	unused, err := run(*queries, flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dotsqlx-unused:", err)
+		os.Exit(2)
+	}
+
+	for _, name := range unused {
+		fmt.Println(name)
+	}
+
+	if len(unused) > 0 {
+		os.Exit(1)
+	}
+}
+
+func run(queries string, patterns []string) ([]string, error) {
+	if queries == "" {
+		return nil, fmt.Errorf("no query files")
+	}
+
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	var files []string
+	for _, p := range strings.Split(queries, ",") {
+		ff, err := filepath.Glob(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, ff...)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no query files match %q", queries)
+	}
+
+	dot, err := dotsqlx.Load(files...)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := querycheck.References("", patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return querycheck.Unused(dot.Names(), refs), nil
+}
@@ -0,0 +1,15 @@
+// Command dotsqlx-vet checks query names passed to dotsqlx methods. It can
+// be run standalone or by go vet:
+//
+//	dotsqlx-vet -queries 'sql/*.sql' ./...
+//	go vet -vettool=$(which dotsqlx-vet) -dotsqlx.queries 'sql/*.sql' ./...
+package main
+
+import (
+	"github.com/swithek/dotsqlx/querycheck"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(querycheck.Analyzer)
+}
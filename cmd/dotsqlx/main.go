@@ -0,0 +1,254 @@
+// Command dotsqlx inspects dotsql files:
+//
+//	dotsqlx list file.sql...            list query names, locations and annotations
+//	dotsqlx show name file.sql...       print the SQL of a query
+//	dotsqlx validate file.sql...        report structural issues
+//	dotsqlx export [-sort] file...      print queries as a single dotsql file
+//	dotsqlx manifest file...            print query fingerprints
+//	dotsqlx fmt [-w] [-upper|-lower] file.sql...
+//	                                    format dotsql files
+//
+// Files with the .yaml, .yml or .json extension are read as query catalogs,
+// so export can be used to convert catalogs into dotsql files.
+//
+// It exits with status 1 if validate finds any issue or show cannot find
+// the query, and with status 2 on usage, I/O or other errors.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/swithek/dotsqlx"
+)
+
+const usage = `usage:
+	dotsqlx list file.sql...
+	dotsqlx show name file.sql...
+	dotsqlx validate file.sql...
+	dotsqlx export [-sort] file...
+	dotsqlx manifest file...
+	dotsqlx fmt [-w] [-upper|-lower] file.sql...`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "fmt" {
+		return format(args[1:], stdout, stderr)
+	}
+
+	if len(args) < 2 || (args[0] == "show" && len(args) < 3) {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	var sorted bool
+
+	files := args[1:]
+	switch {
+	case args[0] == "show":
+		files = args[2:]
+	case args[0] == "export" && args[1] == "-sort":
+		sorted = true
+		files = args[2:]
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	dd, err := dotsqlx.LoadDefinitions(files...)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	switch args[0] {
+	case "list":
+		return list(stdout, dd)
+	case "show":
+		return show(stdout, stderr, dd, args[1])
+	case "validate":
+		return validate(stdout, dd)
+	case "export":
+		return export(stdout, stderr, dd, sorted)
+	case "manifest":
+		return manifest(stdout, stderr, dd)
+	}
+
+	fmt.Fprintln(stderr, usage)
+	return 2
+}
+
+func list(w io.Writer, dd []dotsqlx.Definition) int {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, d := range dd {
+		aa := make([]string, len(d.Annotations))
+		for i, a := range d.Annotations {
+			aa[i] = a.Key + ": " + a.Value
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", d.Name, d.Location(), strings.Join(aa, "; "))
+	}
+
+	tw.Flush()
+	return 0
+}
+
+func show(stdout, stderr io.Writer, dd []dotsqlx.Definition, name string) int {
+	// the last definition wins, as it does with dotsql.Merge
+	for i := len(dd) - 1; i >= 0; i-- {
+		if dd[i].Name == name {
+			fmt.Fprintln(stdout, dd[i].SQL)
+			return 0
+		}
+	}
+
+	fmt.Fprintf(stderr, "dotsqlx: query %q not found\n", name)
+	return 1
+}
+
+func validate(w io.Writer, dd []dotsqlx.Definition) int {
+	ii := dotsqlx.Validate(dd)
+	for _, i := range ii {
+		fmt.Fprintln(w, i)
+	}
+
+	if len(ii) > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func export(stdout, stderr io.Writer, dd []dotsqlx.Definition, sorted bool) int {
+	if err := dotsqlx.Export(stdout, dd, dotsqlx.ExportOptions{Sort: sorted}); err != nil {
+		return fail(stderr, err)
+	}
+
+	return 0
+}
+
+// nameRe matches the `-- name:` tag.
+var nameRe = regexp.MustCompile(`^\s*--\s*name:`)
+
+func format(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+
+	var (
+		write = fs.Bool("w", false, "")
+		upper = fs.Bool("upper", false, "")
+		lower = fs.Bool("lower", false, "")
+	)
+
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 || (*upper && *lower) {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	var opts dotsqlx.NormalizeOptions
+	switch {
+	case *upper:
+		opts.KeywordCase = dotsqlx.UpperCase
+	case *lower:
+		opts.KeywordCase = dotsqlx.LowerCase
+	}
+
+	for _, file := range fs.Args() {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fail(stderr, err)
+		}
+
+		res, err := formatFile(src, file, opts)
+		if err != nil {
+			return fail(stderr, err)
+		}
+
+		if !*write {
+			stdout.Write(res)
+			continue
+		}
+
+		if bytes.Equal(src, res) {
+			continue
+		}
+
+		if err := ioutil.WriteFile(file, res, 0o644); err != nil {
+			return fail(stderr, err)
+		}
+	}
+
+	return 0
+}
+
+// formatFile formats the queries of a dotsql file. Lines preceding the
+// first query, which dotsql ignores, are kept as they are.
+func formatFile(src []byte, file string, opts dotsqlx.NormalizeOptions) ([]byte, error) {
+	dd, err := dotsqlx.ParseDefinitions(bytes.NewReader(src), file)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, line := range strings.SplitAfter(string(src), "\n") {
+		if nameRe.MatchString(line) {
+			break
+		}
+
+		buf.WriteString(line)
+	}
+
+	if header := bytes.TrimRight(buf.Bytes(), " \t\r\n"); len(header) > 0 {
+		buf.Truncate(len(header))
+		buf.WriteString("\n\n")
+	} else {
+		buf.Reset()
+	}
+
+	for i := range dd {
+		dd[i].SQL = dotsqlx.Format(dd[i].SQL, opts)
+		dd[i].Annotations = dotsqlx.ParseAnnotations(dd[i].SQL)
+	}
+
+	if err := dotsqlx.Export(&buf, dd, dotsqlx.ExportOptions{}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func manifest(stdout, stderr io.Writer, dd []dotsqlx.Definition) int {
+	d, err := dotsqlx.FromDefinitions(dd)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if _, err := d.Manifest().WriteTo(stdout); err != nil {
+		return fail(stderr, err)
+	}
+
+	return 0
+}
+
+// fail prints the error, prefixed with the command name unless the error
+// already is, and returns the exit status of errors.
+func fail(stderr io.Writer, err error) int {
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "dotsqlx: ") {
+		msg = "dotsqlx: " + msg
+	}
+
+	fmt.Fprintln(stderr, msg)
+	return 2
+}
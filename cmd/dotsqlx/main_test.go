@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	cc := map[string]struct {
+		Args   []string
+		Code   int
+		Stdout string
+		Stderr string
+	}{
+		"No args": {
+			Code:   2,
+			Stderr: usage + "\n",
+		},
+		"Unknown command": {
+			Args:   []string{"remove", "testdata/valid.sql"},
+			Code:   2,
+			Stderr: usage + "\n",
+		},
+		"File not found": {
+			Args:   []string{"list", "testdata/missing.sql"},
+			Code:   2,
+			Stderr: "dotsqlx: open testdata/missing.sql: no such file or directory\n",
+		},
+		"List": {
+			Args:   []string{"list", "testdata/valid.sql"},
+			Stdout: "select_users  testdata/valid.sql:1  tags: admin\ndelete_user   testdata/valid.sql:5  \n",
+		},
+		"Show not found": {
+			Args:   []string{"show", "update_user", "testdata/valid.sql"},
+			Code:   1,
+			Stderr: "dotsqlx: query \"update_user\" not found\n",
+		},
+		"Show": {
+			Args:   []string{"show", "select_users", "testdata/valid.sql", "testdata/invalid.sql"},
+			Stdout: "SELECT * FROM users WHERE name = 'a\n",
+		},
+		"Validate with issues": {
+			Args:   []string{"validate", "testdata/valid.sql", "testdata/invalid.sql"},
+			Code:   1,
+			Stdout: "testdata/invalid.sql:1: select_users: unbalanced quotes or comments\n",
+		},
+		"Validate": {
+			Args: []string{"validate", "testdata/valid.sql"},
+		},
+		"Validate catalog with metadata": {
+			Args: []string{"validate", "../../testdata/catalog.yaml", "../../testdata/catalog.json"},
+		},
+		"Validate fixture dependencies": {
+			Args: []string{"validate", "../../fixture/testdata/queries.sql"},
+		},
+		"Fmt without files": {
+			Args:   []string{"fmt", "-w"},
+			Code:   2,
+			Stderr: usage + "\n",
+		},
+		"Fmt with conflicting flags": {
+			Args:   []string{"fmt", "-upper", "-lower", "testdata/messy.sql"},
+			Code:   2,
+			Stderr: usage + "\n",
+		},
+		"Fmt file not found": {
+			Args:   []string{"fmt", "testdata/missing.sql"},
+			Code:   2,
+			Stderr: "dotsqlx: open testdata/missing.sql: no such file or directory\n",
+		},
+		"Fmt": {
+			Args:   []string{"fmt", "-upper", "testdata/messy.sql"},
+			Stdout: "-- Queries of the users service.\n\n-- name: select_users\n-- tags: admin\nSELECT * FROM users\nWHERE name = 'a  b'\n\n-- name: delete_user\nDELETE FROM users WHERE id = ?\n",
+		},
+		"Manifest": {
+			Args:   []string{"manifest", "testdata/valid.sql"},
+			Stdout: "73ffdf5be39aa5c4c160c2f77d6634a6970eeb4e1d3395f045ded747f0ce9d2a  delete_user\n26e7e05427bc7dabcd7815d27764fda2baf4cfe60a2d2d6ee2a1f773dccbbce2  select_users\n",
+		},
+		"Manifest with invalid variants": {
+			Args:   []string{"manifest", "testdata/variants.sql"},
+			Code:   2,
+			Stderr: "dotsqlx: testdata/variants.sql:5: select_now: duplicate variant for dialect \"postgres\"\n",
+		},
+		"Export without files": {
+			Args:   []string{"export", "-sort"},
+			Code:   2,
+			Stderr: usage + "\n",
+		},
+		"Export": {
+			Args:   []string{"export", "testdata/valid.sql", "testdata/catalog.yaml"},
+			Stdout: "-- name: select_users\n-- tags: admin\nSELECT * FROM users\n\n-- name: delete_user\nDELETE FROM users WHERE id = ?\n\n-- name: count_users\n-- description: Counts users\nSELECT COUNT(*) FROM users\n",
+		},
+		"Export sorted": {
+			Args:   []string{"export", "-sort", "testdata/valid.sql", "testdata/catalog.yaml"},
+			Stdout: "-- name: count_users\n-- description: Counts users\nSELECT COUNT(*) FROM users\n\n-- name: delete_user\nDELETE FROM users WHERE id = ?\n\n-- name: select_users\n-- tags: admin\nSELECT * FROM users\n",
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, c.Code, run(c.Args, &stdout, &stderr))
+			assert.Equal(t, c.Stdout, stdout.String())
+			assert.Equal(t, c.Stderr, stderr.String())
+		})
+	}
+}
+
+func TestExportValidate(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"export", "../../testdata/catalog.yaml"}, &stdout, &stderr))
+
+	file := filepath.Join(t.TempDir(), "q.sql")
+	require.Nil(t, ioutil.WriteFile(file, stdout.Bytes(), 0o644))
+
+	stdout.Reset()
+	assert.Equal(t, 0, run([]string{"validate", file}, &stdout, &stderr))
+	assert.Empty(t, stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestFmtWrite(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/messy.sql")
+	require.Nil(t, err)
+
+	file := filepath.Join(t.TempDir(), "q.sql")
+	require.Nil(t, ioutil.WriteFile(file, src, 0o644))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"fmt", "-w", "-lower", file}, &stdout, &stderr))
+	assert.Empty(t, stdout.String())
+
+	res, err := ioutil.ReadFile(file)
+	require.Nil(t, err)
+	assert.Equal(t, "-- Queries of the users service.\n\n-- name: select_users\n-- tags: admin\nselect * from users\nwhere name = 'a  b'\n\n-- name: delete_user\ndelete from users where id = ?\n", string(res))
+
+	// formatting is idempotent
+	require.Equal(t, 0, run([]string{"fmt", "-lower", file}, &stdout, &stderr))
+	assert.Equal(t, string(res), stdout.String())
+}
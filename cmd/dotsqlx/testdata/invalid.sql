@@ -0,0 +1,2 @@
+-- name: select_users
+SELECT * FROM users WHERE name = 'a
@@ -0,0 +1,9 @@
+-- Queries of the users service.
+
+-- name: select_users
+-- tags:   admin
+  select *   from users
+	where name = 'a  b'
+
+-- name: delete_user
+delete from users where id = ?
@@ -0,0 +1,6 @@
+-- name: select_users
+-- tags: admin
+SELECT * FROM users
+
+-- name: delete_user
+DELETE FROM users WHERE id = ?
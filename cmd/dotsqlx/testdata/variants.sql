@@ -0,0 +1,7 @@
+-- name: select_now
+-- dialect: postgres
+SELECT now()
+
+-- name: select_now
+-- dialect: postgres
+SELECT CURRENT_TIMESTAMP
@@ -0,0 +1,108 @@
+package dotsqlx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// nameRe matches the `-- name:` tag, the same way dotsql does.
+var nameRe = regexp.MustCompile(`^\s*--\s*name:\s*(\S+)`)
+
+// Definition is a named query as written in a dotsql file. Unlike
+// dotsql.DotSql, definitions keep their source location and duplicates.
+type Definition struct {
+	Name        string
+	SQL         string
+	File        string
+	Line        int
+	Annotations Annotations
+}
+
+// Location returns the source location of the `-- name:` tag.
+func (d Definition) Location() string {
+	return fmt.Sprintf("%s:%d", d.File, d.Line)
+}
+
+// ParseDefinitions reads all named query definitions from r. Query bodies
+// are built the same way dotsql builds them. The file name is only used
+// for source locations.
+func ParseDefinitions(r io.Reader, file string) ([]Definition, error) {
+	var (
+		dd   []Definition
+		body []string
+	)
+
+	flush := func() {
+		if len(dd) == 0 {
+			return
+		}
+
+		d := &dd[len(dd)-1]
+		d.SQL = strings.Join(body, "\n")
+		d.Annotations = ParseAnnotations(d.SQL)
+		body = nil
+	}
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		if m := nameRe.FindStringSubmatch(sc.Text()); m != nil {
+			flush()
+			dd = append(dd, Definition{Name: m[1], File: file, Line: line})
+			continue
+		}
+
+		if len(dd) == 0 {
+			continue
+		}
+
+		if l := strings.Trim(sc.Text(), " \t"); l != "" {
+			body = append(body, l)
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	flush()
+
+	return dd, nil
+}
+
+// LoadDefinitions reads the named query definitions of all files, in the
+// order of the files. Files with the .yaml, .yml or .json extension are
+// read as catalogs with ParseYAML or ParseJSON, all others as dotsql files.
+func LoadDefinitions(files ...string) ([]Definition, error) {
+	var dd []Definition
+
+	for _, file := range files {
+		parse := ParseDefinitions
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml":
+			parse = ParseYAML
+		case ".json":
+			parse = ParseJSON
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+
+		fdd, err := parse(f, file)
+		f.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		dd = append(dd, fdd...)
+	}
+
+	return dd, nil
+}
@@ -0,0 +1,52 @@
+package dotsqlx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDefinitions(t *testing.T) {
+	dd, err := ParseDefinitions(strings.NewReader(`
+SELECT ignored
+
+-- name: select
+-- tags: admin
+  SELECT nr
+	FROM numbers
+
+-- name: select
+--name: empty
+`), "q.sql")
+	require.Nil(t, err)
+	assert.Equal(t, []Definition{
+		{
+			Name:        "select",
+			SQL:         "-- tags: admin\nSELECT nr\nFROM numbers",
+			File:        "q.sql",
+			Line:        4,
+			Annotations: Annotations{{Key: "tags", Value: "admin"}},
+		},
+		{Name: "select", File: "q.sql", Line: 9},
+		{Name: "empty", File: "q.sql", Line: 10},
+	}, dd)
+	assert.Equal(t, "q.sql:4", dd[0].Location())
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	// file not found
+	dd, err := LoadDefinitions("testdata/missing.sql")
+	assert.Nil(t, dd)
+	assert.NotNil(t, err)
+
+	// successful call
+	dd, err = LoadDefinitions("gen/testdata/queries.sql", "querycheck/testdata/queries.sql")
+	require.Nil(t, err)
+	require.Len(t, dd, 6)
+	assert.Equal(t, "select_users", dd[0].Name)
+	assert.Equal(t, "gen/testdata/queries.sql", dd[0].File)
+	assert.Equal(t, "select_users", dd[3].Name)
+	assert.Equal(t, "querycheck/testdata/queries.sql", dd[3].File)
+}
@@ -0,0 +1,165 @@
+package dotsqlx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoVariant is returned when a query has dialect-specific variants but
+// neither one for the executor's dialect nor a dialect-neutral one.
+var ErrNoVariant = errors.New("dotsqlx: no suitable query variant")
+
+// driverDialects maps well known drivers to the dialect names used by the
+// `-- dialect:` annotation.
+var driverDialects = map[string]string{
+	"sqlite3":          "sqlite",
+	"sqlite":           "sqlite",
+	"postgres":         "postgres",
+	"pgx":              "postgres",
+	"cloudsqlpostgres": "postgres",
+	"mysql":            "mysql",
+	"sqlserver":        "sqlserver",
+	"mssql":            "sqlserver",
+}
+
+// Dialect returns the dialect name of the driver, e.g. "postgres" for
+// "pgx". Unknown driver names are returned as they are.
+func Dialect(driverName string) string {
+	if d, ok := driverDialects[driverName]; ok {
+		return d
+	}
+
+	return driverName
+}
+
+// driverNamer is implemented by executors that know their driver, e.g.
+// *sqlx.DB and *sqlx.Tx.
+type driverNamer interface {
+	DriverName() string
+}
+
+// WithVariants returns a copy of DotSqlx that uses dialect-specific
+// variants of queries. A variant is a definition annotated with
+// `-- dialect: <name>`; a definition of the same name without the
+// annotation is the dialect-neutral fallback. Definitions of names that
+// have no annotated variant are ignored. A variant defined again in a later
+// file replaces the earlier one. As with RebindAll, the copy holds a
+// snapshot of the queries.
+//
+// dotsql merges duplicate names into a single query, so the definitions
+// must be loaded with LoadDefinitions or ParseDefinitions.
+func (d DotSqlx) WithVariants(dd []Definition) (*DotSqlx, error) {
+	vv := make(map[string]map[string]string)
+	for _, def := range dd {
+		if def.Annotations.Get("dialect") != "" {
+			vv[def.Name] = make(map[string]string)
+		}
+	}
+
+	files := make(map[string]string)
+	for _, def := range dd {
+		v, ok := vv[def.Name]
+		if !ok {
+			continue
+		}
+
+		// As with Load, a variant in a later file replaces the earlier
+		// one.
+		dialect := def.Annotations.Get("dialect")
+		key := def.Name + "\x00" + dialect
+		if _, ok := v[dialect]; ok && files[key] == def.File {
+			return nil, fmt.Errorf("dotsqlx: %s: %s: duplicate variant for dialect %q", def.Location(), def.Name, dialect)
+		}
+
+		files[key] = def.File
+		v[dialect] = def.SQL
+	}
+
+	qm := d.QueryMap()
+	res := make(map[string]string, len(qm))
+	for name, query := range qm {
+		res[name] = query
+	}
+
+	for name, v := range vv {
+		delete(res, name)
+
+		if query, ok := v[""]; ok {
+			res[name] = query
+		}
+	}
+
+	d.queries = res
+	d.variants = vv
+	return &d, nil
+}
+
+// RawFor returns the variant of dotsql named query for the dialect of the
+// driver, falling back to the dialect-neutral one. ErrNoVariant, wrapped
+// in a *QueryError, is returned if neither exists.
+func (d DotSqlx) RawFor(driverName, name string) (string, error) {
+	return d.resolve(driverName, name)
+}
+
+// track records the name in the tracker, if one is set.
+func (d DotSqlx) track(name string) {
+	if d.tracker != nil {
+		d.tracker.Track(name)
+	}
+}
+
+// resolve returns the variant of dotsql named query for the dialect of the
+// driver, as RawFor does.
+func (d DotSqlx) resolve(driverName, name string) (string, error) {
+	if d.fragments[name] {
+		return "", &QueryError{Name: name, Err: ErrFragment}
+	}
+
+	var (
+		query string
+		err   error
+	)
+
+	if v, ok := d.variants[name]; ok {
+		if query, ok = v[Dialect(driverName)]; !ok {
+			if query, ok = v[""]; !ok {
+				return "", &QueryError{Name: name, Err: ErrNoVariant}
+			}
+		}
+	} else if query, err = d.lookup(name); err != nil {
+		return "", err
+	}
+
+	return query, nil
+}
+
+// raw returns the variant of dotsql named query for the executor's
+// dialect, if the executor exposes its driver name. It is used by the
+// methods executing queries, so the name is recorded by the tracker, if
+// one is set.
+func (d DotSqlx) raw(db interface{}, name string) (string, error) {
+	var driverName string
+	if dn, ok := db.(driverNamer); ok {
+		driverName = dn.DriverName()
+	}
+
+	query, err := d.resolve(driverName, name)
+	if err != nil {
+		return "", err
+	}
+
+	d.track(name)
+
+	return query, nil
+}
+
+// rawNamed returns the query like raw, without comments, whose colons
+// jmoiron/sqlx would take for named parameters.
+func (d DotSqlx) rawNamed(db interface{}, name string) (string, error) {
+	query, err := d.raw(db, name)
+	if err != nil {
+		return "", err
+	}
+
+	return stripComments(query), nil
+}
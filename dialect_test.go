@@ -0,0 +1,191 @@
+package dotsqlx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qustavo/dotsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dialectQueries = `
+-- name: select_now
+-- dialect: postgres
+SELECT CAST(now() AS TEXT)
+
+-- name: select_now
+-- dialect: sqlite
+SELECT datetime('now')
+
+-- name: select_now
+SELECT CURRENT_TIMESTAMP
+
+-- name: select_pg
+-- dialect: postgres
+SELECT 1
+
+-- name: select_nr
+SELECT nr FROM numbers
+`
+
+func newVariantsDot(t *testing.T, q string) *DotSqlx {
+	d, err := dotsql.LoadFromString(q)
+	require.Nil(t, err)
+
+	dd, err := ParseDefinitions(strings.NewReader(q), "q.sql")
+	require.Nil(t, err)
+
+	dot, err := Wrap(d).WithVariants(dd)
+	require.Nil(t, err)
+
+	return dot
+}
+
+func TestDialect(t *testing.T) {
+	assert.Equal(t, "postgres", Dialect("pgx"))
+	assert.Equal(t, "sqlite", Dialect("sqlite3"))
+	assert.Equal(t, "oracle", Dialect("oracle"))
+}
+
+func TestWithVariants(t *testing.T) {
+	dd, err := ParseDefinitions(strings.NewReader(`
+-- name: select
+-- dialect: sqlite
+SELECT 1
+
+-- name: select
+-- dialect: sqlite
+SELECT 2
+`), "q.sql")
+	require.Nil(t, err)
+
+	_, err = newDot(t, "").WithVariants(dd)
+	assert.EqualError(t, err, `dotsqlx: q.sql:6: select: duplicate variant for dialect "sqlite"`)
+
+	// variant replaced by a later file
+	dd[1].File = "o.sql"
+	dot, err := newDot(t, "").WithVariants(dd)
+	require.Nil(t, err)
+
+	query, err := dot.RawFor("sqlite3", "select")
+	require.Nil(t, err)
+	assert.Equal(t, "-- dialect: sqlite\nSELECT 2", query)
+
+	dot = newVariantsDot(t, dialectQueries)
+	assert.Equal(t, []string{"select_now", "select_nr", "select_pg"}, dot.Names())
+	assert.Equal(t, map[string]string{
+		"select_now": "SELECT CURRENT_TIMESTAMP",
+		"select_nr":  "SELECT nr FROM numbers",
+	}, dot.QueryMap())
+}
+
+func TestRawFor(t *testing.T) {
+	dot := newVariantsDot(t, dialectQueries)
+
+	cc := map[string]struct {
+		Driver string
+		Name   string
+		Query  string
+		Err    error
+	}{
+		"dialect variant": {
+			Driver: "pgx",
+			Name:   "select_now",
+			Query:  "-- dialect: postgres\nSELECT CAST(now() AS TEXT)",
+		},
+		"neutral fallback": {
+			Driver: "mysql",
+			Name:   "select_now",
+			Query:  "SELECT CURRENT_TIMESTAMP",
+		},
+		"no variant": {
+			Driver: "sqlite3",
+			Name:   "select_pg",
+			Err:    &QueryError{Name: "select_pg", Err: ErrNoVariant},
+		},
+		"no variants": {
+			Driver: "sqlite3",
+			Name:   "select_nr",
+			Query:  "SELECT nr FROM numbers",
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := dot.RawFor(c.Driver, c.Name)
+			assert.Equal(t, c.Err, err)
+			assert.Equal(t, c.Query, query)
+		})
+	}
+
+	_, err := dot.Raw("select_pg")
+	assert.Equal(t, &QueryError{Name: "select_pg", Err: ErrNoVariant}, err)
+
+	_, err = dot.RawFor("sqlite3", "missing")
+	assert.NotNil(t, err)
+}
+
+func TestLookupFor(t *testing.T) {
+	dot := newVariantsDot(t, dialectQueries)
+
+	// no neutral variant
+	_, err := dot.Lookup("select_pg")
+	assert.Equal(t, &QueryError{Name: "select_pg", Err: ErrNoVariant}, err)
+
+	_, err = dot.LookupFor("sqlite3", "select_pg")
+	assert.Equal(t, &QueryError{Name: "select_pg", Err: ErrNoVariant}, err)
+
+	// successful call
+	q, err := dot.LookupFor("pgx", "select_pg")
+	require.Nil(t, err)
+	assert.Equal(t, "select_pg", q.Name)
+	assert.Equal(t, "-- dialect: postgres\nSELECT 1", q.SQL)
+	assert.Equal(t, "postgres", q.Annotations.Get("dialect"))
+}
+
+func TestInFor(t *testing.T) {
+	dot := newVariantsDot(t, `
+-- name: select_in
+-- dialect: postgres
+SELECT nr FROM numbers WHERE nr IN ($1)`)
+
+	// no neutral variant
+	_, _, err := dot.In("select_in", []int{1, 2})
+	assert.Equal(t, &QueryError{Name: "select_in", Err: ErrNoVariant}, err)
+
+	_, err = dot.InChunks(10, "select_in", []int{1, 2})
+	assert.Equal(t, &QueryError{Name: "select_in", Err: ErrNoVariant}, err)
+
+	// successful calls
+	query, args, err := dot.InFor("postgres", "select_in", []int{1, 2})
+	require.Nil(t, err)
+	assert.Equal(t, "-- dialect: postgres\nSELECT nr FROM numbers WHERE nr IN ($1, $2)", query)
+	assert.Equal(t, []interface{}{1, 2}, args)
+
+	cc, err := dot.InChunksFor("postgres", 1, "select_in", []int{1, 2})
+	require.Nil(t, err)
+	require.Len(t, cc, 2)
+	assert.Equal(t, "-- dialect: postgres\nSELECT nr FROM numbers WHERE nr IN ($1)", cc[1].Query)
+	assert.Equal(t, []interface{}{2}, cc[1].Args)
+}
+
+func TestVariantsExecutor(t *testing.T) {
+	db := newDB(t, 0)
+	dot := newVariantsDot(t, dialectQueries)
+
+	var now string
+	require.Nil(t, dot.Get(db, &now, "select_now"))
+	assert.NotEmpty(t, now)
+
+	var n int
+	assert.Equal(t, &QueryError{Name: "select_pg", Err: ErrNoVariant}, dot.Get(db, &n, "select_pg"))
+
+	res, err := dot.RebindAll(0)
+	require.Nil(t, err)
+	require.Nil(t, res.Get(db, &now, "select_now"))
+}
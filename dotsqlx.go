@@ -3,6 +3,7 @@ package dotsqlx
 import (
 	"context"
 	"database/sql"
+	"sort"
 
 	"github.com/qustavo/dotsql"
 	"github.com/jmoiron/sqlx"
@@ -108,22 +109,231 @@ type NamedBinder interface {
 	BindNamed(query string, arg interface{}) (string, []interface{}, error)
 }
 
+// NamedGetter is an interface used by NamedGet.
+type NamedGetter interface {
+	NamedBinder
+	Getter
+}
+
+// NamedGetterContext is an interface used by NamedGetContext.
+type NamedGetterContext interface {
+	NamedBinder
+	GetterContext
+}
+
+// NamedSelecter is an interface used by NamedSelect.
+type NamedSelecter interface {
+	NamedBinder
+	Selecter
+}
+
+// NamedSelecterContext is an interface used by NamedSelectContext.
+type NamedSelecterContext interface {
+	NamedBinder
+	SelecterContext
+}
+
+// ExtContext is a union interface which can bind, query, get, select and
+// exec, implemented by both sqlx.DB and sqlx.Tx.
+type ExtContext interface {
+	sqlx.ExtContext
+	GetterContext
+	SelecterContext
+}
+
 // DotSqlx wraps dotsql.DotSql instance and allows seamless work with
 // jmoiron/sqlx.
 type DotSqlx struct {
 	*dotsql.DotSql
+
+	tracker *Tracker
+
+	// queries, if not nil, replaces the queries of the embedded DotSql,
+	// e.g. after they have been rebound.
+	queries map[string]string
+
+	// variants holds dialect-specific variants of queries, keyed by query
+	// name and dialect. The dialect-neutral variant has an empty key.
+	variants map[string]map[string]string
+
+	// fragments holds the names of queries that can only be included in
+	// other queries.
+	fragments map[string]bool
+
+	// source provides the queries. The embedded DotSql is used if it is
+	// nil.
+	source Source
 }
 
 // Wrap creates a new DotSqlx instance and embeds provided dotsql.DotSql
 // instance into it.
 func Wrap(d *dotsql.DotSql) *DotSqlx {
-	return &DotSqlx{d}
+	return &DotSqlx{DotSql: d, source: d}
+}
+
+// WithTracker returns a copy of DotSqlx that records the names of all
+// executed, or prepared, queries in t, including the ones executed via
+// Query, StmtCache and Templates. Calls that only return the SQL, such as
+// Raw, In, Lookup, Annotations, Templates.Render and PrepareAll, are not
+// recorded.
+func (d DotSqlx) WithTracker(t *Tracker) *DotSqlx {
+	d.tracker = t
+	return &d
+}
+
+// Raw returns dotsql named query, or its dialect-neutral variant.
+func (d DotSqlx) Raw(name string) (string, error) {
+	return d.RawFor("", name)
+}
+
+// QueryMap returns a map of all loaded named queries.
+func (d DotSqlx) QueryMap() map[string]string {
+	if d.queries != nil {
+		return d.queries
+	}
+
+	return d.Source().QueryMap()
+}
+
+// Source returns the source of the queries.
+func (d DotSqlx) Source() Source {
+	if d.source == nil {
+		return d.DotSql
+	}
+
+	return d.source
+}
+
+// lookup returns the loaded query.
+func (d DotSqlx) lookup(name string) (string, error) {
+	if d.queries == nil {
+		query, err := d.Source().Raw(name)
+		if _, ok := d.Source().(*dotsql.DotSql); ok && err != nil {
+			// dotsql fails only if the query does not exist
+			return "", &QueryError{Name: name, Err: ErrNotFound}
+		}
+
+		return query, err
+	}
+
+	query, ok := d.queries[name]
+	if !ok {
+		return "", &QueryError{Name: name, Err: ErrNotFound}
+	}
+
+	return query, nil
+}
+
+// Names returns the sorted names of all loaded queries.
+func (d DotSqlx) Names() []string {
+	qm := d.QueryMap()
+
+	names := make([]string, 0, len(qm)+len(d.variants))
+	for name := range qm {
+		names = append(names, name)
+	}
+
+	for name := range d.variants {
+		if _, ok := qm[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Prepare is a wrapper for database/sql's Prepare(), using dotsql named
+// query.
+func (d DotSqlx) Prepare(db dotsql.Preparer, name string) (*sql.Stmt, error) {
+	query, err := d.raw(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Prepare(query)
+}
+
+// PrepareContext is a wrapper for database/sql's PrepareContext(), using
+// dotsql named query.
+func (d DotSqlx) PrepareContext(ctx context.Context, db dotsql.PreparerContext, name string) (*sql.Stmt, error) {
+	query, err := d.raw(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.PrepareContext(ctx, query)
+}
+
+// Query is a wrapper for database/sql's Query(), using dotsql named query.
+func (d DotSqlx) Query(db dotsql.Queryer, name string, args ...interface{}) (*sql.Rows, error) {
+	query, err := d.raw(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Query(query, args...)
+}
+
+// QueryContext is a wrapper for database/sql's QueryContext(), using dotsql
+// named query.
+func (d DotSqlx) QueryContext(ctx context.Context, db dotsql.QueryerContext, name string, args ...interface{}) (*sql.Rows, error) {
+	query, err := d.raw(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.QueryContext(ctx, query, args...)
+}
+
+// QueryRow is a wrapper for database/sql's QueryRow(), using dotsql named
+// query.
+func (d DotSqlx) QueryRow(db dotsql.QueryRower, name string, args ...interface{}) (*sql.Row, error) {
+	query, err := d.raw(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.QueryRow(query, args...), nil
+}
+
+// QueryRowContext is a wrapper for database/sql's QueryRowContext(), using
+// dotsql named query.
+func (d DotSqlx) QueryRowContext(ctx context.Context, db dotsql.QueryRowerContext, name string, args ...interface{}) (*sql.Row, error) {
+	query, err := d.raw(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.QueryRowContext(ctx, query, args...), nil
+}
+
+// Exec is a wrapper for database/sql's Exec(), using dotsql named query.
+func (d DotSqlx) Exec(db dotsql.Execer, name string, args ...interface{}) (sql.Result, error) {
+	query, err := d.raw(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Exec(query, args...)
+}
+
+// ExecContext is a wrapper for database/sql's ExecContext(), using dotsql
+// named query.
+func (d DotSqlx) ExecContext(ctx context.Context, db dotsql.ExecerContext, name string, args ...interface{}) (sql.Result, error) {
+	query, err := d.raw(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.ExecContext(ctx, query, args...)
 }
 
 // Preparex is a wrapper for jmoiron/sqlx's Preparex(), using dotsql named
 // query.
 func (d DotSqlx) Preparex(dbx Preparerx, name string) (*sqlx.Stmt, error) {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +344,7 @@ func (d DotSqlx) Preparex(dbx Preparerx, name string) (*sqlx.Stmt, error) {
 // PreparexContext is a wrapper for jmoiron/sqlx's PreparexContext(), using
 // dotsql named query.
 func (d DotSqlx) PreparexContext(ctx context.Context, dbx PreparerxContext, name string) (*sqlx.Stmt, error) {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -144,7 +354,7 @@ func (d DotSqlx) PreparexContext(ctx context.Context, dbx PreparerxContext, name
 
 // Get is a wrapper for jmoiron/sqlx's Get(), using dotsql named query.
 func (d DotSqlx) Get(dbx Getter, dest interface{}, name string, args ...interface{}) error {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return err
 	}
@@ -155,7 +365,7 @@ func (d DotSqlx) Get(dbx Getter, dest interface{}, name string, args ...interfac
 // GetContext is a wrapper for jmoiron/sqlx's GetContext(), using dotsql
 // named query.
 func (d DotSqlx) GetContext(ctx context.Context, dbx GetterContext, dest interface{}, name string, args ...interface{}) error {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return err
 	}
@@ -165,7 +375,7 @@ func (d DotSqlx) GetContext(ctx context.Context, dbx GetterContext, dest interfa
 
 // Select is a wrapper for jmoiron/sqlx's Select(), using dotsql named query.
 func (d DotSqlx) Select(dbx Selecter, dest interface{}, name string, args ...interface{}) error {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return err
 	}
@@ -176,7 +386,7 @@ func (d DotSqlx) Select(dbx Selecter, dest interface{}, name string, args ...int
 // SelectContext is a wrapper for jmoiron/sqlx's SelectContext(), using
 // dotsql named query.
 func (d DotSqlx) SelectContext(ctx context.Context, dbx SelecterContext, dest interface{}, name string, args ...interface{}) error {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return err
 	}
@@ -186,7 +396,7 @@ func (d DotSqlx) SelectContext(ctx context.Context, dbx SelecterContext, dest in
 
 // Queryx is a wrapper for jmoiron/sqlx's Queryx(), using dotsql named query.
 func (d DotSqlx) Queryx(dbx Queryerx, name string, args ...interface{}) (*sqlx.Rows, error) {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -197,7 +407,7 @@ func (d DotSqlx) Queryx(dbx Queryerx, name string, args ...interface{}) (*sqlx.R
 // QueryxContext is a wrapper for jmoiron/sqlx's QueryxContext(), using
 // dotsql named query.
 func (d DotSqlx) QueryxContext(ctx context.Context, dbx QueryerxContext, name string, args ...interface{}) (*sqlx.Rows, error) {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +418,7 @@ func (d DotSqlx) QueryxContext(ctx context.Context, dbx QueryerxContext, name st
 // QueryRowx is a wrapper for jmoiron/sqlx's QueryRowx(), using dotsql
 // named query.
 func (d DotSqlx) QueryRowx(dbx QueryRowerx, name string, args ...interface{}) (*sqlx.Row, error) {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -219,7 +429,7 @@ func (d DotSqlx) QueryRowx(dbx QueryRowerx, name string, args ...interface{}) (*
 // QueryRowxContext is a wrapper for jmoiron/sqlx's QueryRowxContext(), using
 // dotsql named query.
 func (d DotSqlx) QueryRowxContext(ctx context.Context, dbx QueryRowerxContext, name string, args ...interface{}) (*sqlx.Row, error) {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -230,7 +440,7 @@ func (d DotSqlx) QueryRowxContext(ctx context.Context, dbx QueryRowerxContext, n
 // MustExec is a wrapper for jmoiron/sqlx's MustExec(), using dotsql named
 // query.
 func (d DotSqlx) MustExec(dbx MustExecer, name string, args ...interface{}) sql.Result {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		panic(err)
 	}
@@ -241,7 +451,7 @@ func (d DotSqlx) MustExec(dbx MustExecer, name string, args ...interface{}) sql.
 // MustExecContext is a wrapper for jmoiron/sqlx's MustExecContext(), using
 // dotsql named query.
 func (d DotSqlx) MustExecContext(ctx context.Context, dbx MustExecerContext, name string, args ...interface{}) sql.Result {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		panic(err)
 	}
@@ -251,7 +461,7 @@ func (d DotSqlx) MustExecContext(ctx context.Context, dbx MustExecerContext, nam
 
 // Rebind is a wrapper for jmoiron/sqlx's Rebind(), using dotsql named query.
 func (d DotSqlx) Rebind(dbx Rebinder, name string) (string, error) {
-	query, err := d.Raw(name)
+	query, err := d.raw(dbx, name)
 	if err != nil {
 		return "", err
 	}
@@ -262,7 +472,7 @@ func (d DotSqlx) Rebind(dbx Rebinder, name string) (string, error) {
 // PrepareNamed is a wrapper for jmoiron/sqlx's PrepareNamed(), using dotsql
 // named query.
 func (d DotSqlx) PrepareNamed(dbx NamedPreparer, name string) (*sqlx.NamedStmt, error) {
-	query, err := d.Raw(name)
+	query, err := d.rawNamed(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -273,7 +483,7 @@ func (d DotSqlx) PrepareNamed(dbx NamedPreparer, name string) (*sqlx.NamedStmt,
 // PrepareNamedContext is a wrapper for jmoiron/sqlx's PrepareNamedContext(),
 // using dotsql named query.
 func (d DotSqlx) PrepareNamedContext(ctx context.Context, dbx NamedPreparerContext, name string) (*sqlx.NamedStmt, error) {
-	query, err := d.Raw(name)
+	query, err := d.rawNamed(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -284,7 +494,7 @@ func (d DotSqlx) PrepareNamedContext(ctx context.Context, dbx NamedPreparerConte
 // NamedQuery is a wrapper for jmoiron/sqlx's NamedQuery(), using dotsql
 // named query.
 func (d DotSqlx) NamedQuery(dbx NamedQueryer, name string, arg interface{}) (*sqlx.Rows, error) {
-	query, err := d.Raw(name)
+	query, err := d.rawNamed(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -295,7 +505,7 @@ func (d DotSqlx) NamedQuery(dbx NamedQueryer, name string, arg interface{}) (*sq
 // NamedQueryContext is a wrapper for jmoiron/sqlx's NamedQueryContext(),
 // using dotsql named query.
 func (d DotSqlx) NamedQueryContext(ctx context.Context, dbx NamedQueryerContext, name string, arg interface{}) (*sqlx.Rows, error) {
-	query, err := d.Raw(name)
+	query, err := d.rawNamed(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -306,7 +516,7 @@ func (d DotSqlx) NamedQueryContext(ctx context.Context, dbx NamedQueryerContext,
 // NamedExec is a wrapper for jmoiron/sqlx's NamedExec(), using dotsql
 // named query.
 func (d DotSqlx) NamedExec(dbx NamedExecer, name string, arg interface{}) (sql.Result, error) {
-	query, err := d.Raw(name)
+	query, err := d.rawNamed(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -317,7 +527,7 @@ func (d DotSqlx) NamedExec(dbx NamedExecer, name string, arg interface{}) (sql.R
 // NamedExecContext is a wrapper for jmoiron/sqlx's NamedExecContext(),
 // using dotsql named query.
 func (d DotSqlx) NamedExecContext(ctx context.Context, dbx NamedExecerContext, name string, arg interface{}) (sql.Result, error) {
-	query, err := d.Raw(name)
+	query, err := d.rawNamed(dbx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -328,7 +538,7 @@ func (d DotSqlx) NamedExecContext(ctx context.Context, dbx NamedExecerContext, n
 // BindNamed is a wrapper for jmoiron/sqlx's BindNamed(), using dotsql named
 // query.
 func (d DotSqlx) BindNamed(dbx NamedBinder, name string, arg interface{}) (string, []interface{}, error) {
-	query, err := d.Raw(name)
+	query, err := d.rawNamed(dbx, name)
 	if err != nil {
 		return "", nil, err
 	}
@@ -336,12 +546,71 @@ func (d DotSqlx) BindNamed(dbx NamedBinder, name string, arg interface{}) (strin
 	return dbx.BindNamed(query, arg)
 }
 
+// NamedGet binds arg to dotsql named query and executes it via
+// jmoiron/sqlx's Get(). sql.ErrNoRows is returned if the result set is
+// empty.
+func (d DotSqlx) NamedGet(dbx NamedGetter, dest interface{}, name string, arg interface{}) error {
+	query, args, err := d.BindNamed(dbx, name, arg)
+	if err != nil {
+		return err
+	}
+
+	return dbx.Get(dest, query, args...)
+}
+
+// NamedGetContext binds arg to dotsql named query and executes it via
+// jmoiron/sqlx's GetContext(). sql.ErrNoRows is returned if the result set
+// is empty.
+func (d DotSqlx) NamedGetContext(ctx context.Context, dbx NamedGetterContext, dest interface{}, name string, arg interface{}) error {
+	query, args, err := d.BindNamed(dbx, name, arg)
+	if err != nil {
+		return err
+	}
+
+	return dbx.GetContext(ctx, dest, query, args...)
+}
+
+// NamedSelect binds arg to dotsql named query and executes it via
+// jmoiron/sqlx's Select().
+func (d DotSqlx) NamedSelect(dbx NamedSelecter, dest interface{}, name string, arg interface{}) error {
+	query, args, err := d.BindNamed(dbx, name, arg)
+	if err != nil {
+		return err
+	}
+
+	return dbx.Select(dest, query, args...)
+}
+
+// NamedSelectContext binds arg to dotsql named query and executes it via
+// jmoiron/sqlx's SelectContext().
+func (d DotSqlx) NamedSelectContext(ctx context.Context, dbx NamedSelecterContext, dest interface{}, name string, arg interface{}) error {
+	query, args, err := d.BindNamed(dbx, name, arg)
+	if err != nil {
+		return err
+	}
+
+	return dbx.SelectContext(ctx, dest, query, args...)
+}
+
 // In is a wrapper for jmoiron/sqlx's In(), using dotsql named query.
+// Queries written, or rebound, with `$1` or `@p1` bindvars keep their
+// style.
 func (d DotSqlx) In(name string, args ...interface{}) (string, []interface{}, error) {
 	query, err := d.Raw(name)
 	if err != nil {
 		return "", nil, err
 	}
 
-	return sqlx.In(query, args...)
+	return in(query, args...)
+}
+
+// InFor is like In but uses the variant of dotsql named query for the
+// dialect of the driver, falling back to the dialect-neutral one.
+func (d DotSqlx) InFor(driverName, name string, args ...interface{}) (string, []interface{}, error) {
+	query, err := d.RawFor(driverName, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return in(query, args...)
 }
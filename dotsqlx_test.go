@@ -7,6 +7,7 @@ import (
 
 	"github.com/qustavo/dotsql"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -35,7 +36,20 @@ func (s sqlResult) RowsAffected() (int64, error) {
 func newDot(t *testing.T, q string) *DotSqlx {
 	d, err := dotsql.LoadFromString(q)
 	require.Nil(t, err)
-	return &DotSqlx{d}
+	return Wrap(d)
+}
+
+func newDB(t *testing.T, rows int) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+
+	db.MustExec("CREATE TABLE numbers (nr INTEGER)")
+	for i := 0; i < rows; i++ {
+		db.MustExec("INSERT INTO numbers (nr) VALUES (?)", i)
+	}
+
+	return db
 }
 
 func TestWrap(t *testing.T) {
@@ -44,6 +58,109 @@ func TestWrap(t *testing.T) {
 	assert.NotNil(t, d.DotSql)
 }
 
+func TestWithTracker(t *testing.T) {
+	dot := newDot(t, queries)
+	tr := NewTracker()
+
+	d := dot.WithTracker(tr)
+	assert.Nil(t, dot.tracker)
+	assert.Equal(t, tr, d.tracker)
+}
+
+func TestRaw(t *testing.T) {
+	tr := NewTracker()
+	dot := newDot(t, queries).WithTracker(tr)
+
+	// query not found
+	query, err := dot.Raw("select123")
+	assert.Zero(t, query)
+	assert.Equal(t, &QueryError{Name: "select123", Err: ErrNotFound}, err)
+	assert.Empty(t, tr.Executed())
+
+	// successful call
+	query, err = dot.Raw("select")
+	assert.Equal(t, "SELECT nr FROM numbers WHERE nr = ?", query)
+	assert.Nil(t, err)
+	assert.Empty(t, tr.Executed())
+}
+
+func TestNames(t *testing.T) {
+	assert.Equal(t, []string{"insert", "select"}, newDot(t, queries).Names())
+}
+
+func TestDotSqlWrappers(t *testing.T) {
+	tr := NewTracker()
+	dot := newDot(t, `
+-- name: insert
+INSERT INTO numbers (nr) VALUES (?)
+
+-- name: select
+SELECT nr FROM numbers WHERE nr = ?`).WithTracker(tr)
+	db := newDB(t, 0)
+	defer db.Close()
+	ctx := context.Background()
+
+	// query not found
+	_, err := dot.Exec(db, "insert123", 1)
+	assert.NotNil(t, err)
+	_, err = dot.ExecContext(ctx, db, "insert123", 1)
+	assert.NotNil(t, err)
+	_, err = dot.Prepare(db, "select123")
+	assert.NotNil(t, err)
+	_, err = dot.PrepareContext(ctx, db, "select123")
+	assert.NotNil(t, err)
+	_, err = dot.Query(db, "select123", 1)
+	assert.NotNil(t, err)
+	_, err = dot.QueryContext(ctx, db, "select123", 1)
+	assert.NotNil(t, err)
+	_, err = dot.QueryRow(db, "select123", 1)
+	assert.NotNil(t, err)
+	_, err = dot.QueryRowContext(ctx, db, "select123", 1)
+	assert.NotNil(t, err)
+
+	// successful calls
+	_, err = dot.Exec(db, "insert", 1)
+	require.Nil(t, err)
+	_, err = dot.ExecContext(ctx, db, "insert", 2)
+	require.Nil(t, err)
+
+	stmt, err := dot.Prepare(db, "select")
+	require.Nil(t, err)
+	require.Nil(t, stmt.Close())
+
+	stmt, err = dot.PrepareContext(ctx, db, "select")
+	require.Nil(t, err)
+	require.Nil(t, stmt.Close())
+
+	rows, err := dot.Query(db, "select", 1)
+	require.Nil(t, err)
+	assert.True(t, rows.Next())
+	require.Nil(t, rows.Close())
+
+	rows, err = dot.QueryContext(ctx, db, "select", 2)
+	require.Nil(t, err)
+	assert.True(t, rows.Next())
+	require.Nil(t, rows.Close())
+
+	var nr int
+	row, err := dot.QueryRow(db, "select", 1)
+	require.Nil(t, err)
+	require.Nil(t, row.Scan(&nr))
+	assert.Equal(t, 1, nr)
+
+	row, err = dot.QueryRowContext(ctx, db, "select", 2)
+	require.Nil(t, err)
+	require.Nil(t, row.Scan(&nr))
+	assert.Equal(t, 2, nr)
+
+	assert.Equal(t, map[string]int{"insert": 2, "select": 6}, tr.Executed())
+}
+
+func TestExtContext(t *testing.T) {
+	require.Implements(t, (*ExtContext)(nil), new(sqlx.DB))
+	require.Implements(t, (*ExtContext)(nil), new(sqlx.Tx))
+}
+
 func TestPreparex(t *testing.T) {
 	require.Implements(t, (*Preparerx)(nil), new(sqlx.DB))
 
@@ -509,7 +626,7 @@ func TestQueryRowxContext(t *testing.T) {
 	assert.Nil(t, err)
 	ff := q.QueryRowxContextCalls()
 	require.Equal(t, 1, len(ff))
-	assert.NotZero(t, ff[0].Ctx)
+	assert.NotNil(t, ff[0].Ctx)
 	assert.NotZero(t, ff[0].Query)
 	require.NotEmpty(t, ff[0].Args)
 	assert.Equal(t, 1, ff[0].Args[0])
@@ -934,6 +1051,119 @@ func TestBindNamed(t *testing.T) {
 	assert.NotNil(t, ff[0].Arg)
 }
 
+const namedQueries = `
+-- name: select_named
+SELECT nr FROM numbers WHERE nr >= :min ORDER BY nr
+
+-- name: select_named_invalid
+SELECT nr FROM missing WHERE nr >= :min`
+
+func TestNamedGet(t *testing.T) {
+	require.Implements(t, (*NamedGetter)(nil), new(sqlx.DB))
+
+	dot := newDot(t, namedQueries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	// query not found
+	var nr number
+	err := dot.NamedGet(db, &nr, "select_named123", map[string]interface{}{"min": 1})
+	assert.NotNil(t, err)
+
+	// missing named parameter
+	err = dot.NamedGet(db, &nr, "select_named", map[string]interface{}{})
+	assert.NotNil(t, err)
+
+	// error returned by db
+	err = dot.NamedGet(db, &nr, "select_named_invalid", map[string]interface{}{"min": 1})
+	assert.NotNil(t, err)
+
+	// no rows
+	err = dot.NamedGet(db, &nr, "select_named", map[string]interface{}{"min": 5})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// successful call
+	err = dot.NamedGet(db, &nr, "select_named", struct {
+		Min int `db:"min"`
+	}{1})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, nr.Nr)
+}
+
+func TestNamedGetContext(t *testing.T) {
+	require.Implements(t, (*NamedGetterContext)(nil), new(sqlx.DB))
+
+	dot := newDot(t, namedQueries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	// query not found
+	var nr int
+	err := dot.NamedGetContext(context.Background(), db, &nr, "select_named123", map[string]interface{}{"min": 1})
+	assert.NotNil(t, err)
+
+	// error returned by db
+	err = dot.NamedGetContext(context.Background(), db, &nr, "select_named_invalid", map[string]interface{}{"min": 1})
+	assert.NotNil(t, err)
+
+	// no rows
+	err = dot.NamedGetContext(context.Background(), db, &nr, "select_named", map[string]interface{}{"min": 5})
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	// successful call
+	err = dot.NamedGetContext(context.Background(), db, &nr, "select_named", map[string]interface{}{"min": 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, nr)
+}
+
+func TestNamedSelect(t *testing.T) {
+	require.Implements(t, (*NamedSelecter)(nil), new(sqlx.DB))
+
+	dot := newDot(t, namedQueries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	// query not found
+	var nrs []number
+	err := dot.NamedSelect(db, &nrs, "select_named123", map[string]interface{}{"min": 1})
+	assert.NotNil(t, err)
+	assert.Empty(t, nrs)
+
+	// error returned by db
+	err = dot.NamedSelect(db, &nrs, "select_named_invalid", map[string]interface{}{"min": 1})
+	assert.NotNil(t, err)
+	assert.Empty(t, nrs)
+
+	// successful call
+	err = dot.NamedSelect(db, &nrs, "select_named", map[string]interface{}{"min": 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []number{{1}, {2}}, nrs)
+}
+
+func TestNamedSelectContext(t *testing.T) {
+	require.Implements(t, (*NamedSelecterContext)(nil), new(sqlx.DB))
+
+	dot := newDot(t, namedQueries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	// query not found
+	var nrs []int
+	err := dot.NamedSelectContext(context.Background(), db, &nrs, "select_named123", map[string]interface{}{"min": 1})
+	assert.NotNil(t, err)
+	assert.Empty(t, nrs)
+
+	// error returned by db
+	err = dot.NamedSelectContext(context.Background(), db, &nrs, "select_named_invalid", map[string]interface{}{"min": 1})
+	assert.NotNil(t, err)
+	assert.Empty(t, nrs)
+
+	// successful call
+	err = dot.NamedSelectContext(context.Background(), db, &nrs, "select_named", map[string]interface{}{"min": 0})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1, 2}, nrs)
+}
+
 func TestIn(t *testing.T) {
 	dot := newDot(t, `
 --name: select
@@ -0,0 +1,86 @@
+package dotsqlx
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ExportOptions holds the options of Export.
+type ExportOptions struct {
+	// Sort writes the definitions sorted by name instead of in their
+	// order. Definitions sharing a name, e.g. dialect variants, keep their
+	// relative order.
+	Sort bool
+}
+
+// Export writes the definitions to w as a dotsql file: one `-- name:`
+// block per definition, followed by its annotations and SQL, with blocks
+// separated by an empty line. The output only depends on the definitions'
+// names, annotations and SQL, so it can be used to format dotsql files and
+// to convert catalogs into dotsql files.
+func Export(w io.Writer, dd []Definition, opts ExportOptions) error {
+	if opts.Sort {
+		dd = append([]Definition(nil), dd...)
+		sort.SliceStable(dd, func(i, j int) bool {
+			return dd[i].Name < dd[j].Name
+		})
+	}
+
+	bw := bufio.NewWriter(w)
+	for i, d := range dd {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+
+		bw.WriteString("-- name: " + d.Name + "\n")
+
+		for _, a := range d.Annotations {
+			bw.WriteString(strings.TrimRight("-- "+a.Key+": "+a.Value, " ") + "\n")
+		}
+
+		for _, line := range stripAnnotations(d.SQL) {
+			if line != "" {
+				bw.WriteString(line + "\n")
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Definitions returns the loaded queries, sorted by name, as definitions
+// without source locations. Dialect-specific variants follow the
+// dialect-neutral query of the same name.
+func (d DotSqlx) Definitions() []Definition {
+	var dd []Definition
+
+	for _, name := range d.Names() {
+		if query, err := d.lookup(name); err == nil {
+			dd = append(dd, Definition{Name: name, SQL: query, Annotations: d.annotations(name, query)})
+		}
+
+		v := d.variants[name]
+
+		dialects := make([]string, 0, len(v))
+		for dialect := range v {
+			if dialect != "" {
+				dialects = append(dialects, dialect)
+			}
+		}
+
+		sort.Strings(dialects)
+
+		for _, dialect := range dialects {
+			dd = append(dd, Definition{Name: name, SQL: v[dialect], Annotations: ParseAnnotations(v[dialect])})
+		}
+	}
+
+	return dd
+}
+
+// Export writes the loaded queries to w as a dotsql file, sorted by name.
+func (d DotSqlx) Export(w io.Writer) error {
+	return Export(w, d.Definitions(), ExportOptions{})
+}
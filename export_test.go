@@ -0,0 +1,107 @@
+package dotsqlx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	dd, err := ParseDefinitions(strings.NewReader(`
+-- name: b
+--tags:   x
+   SELECT 1
+-- name: a
+-- dialect: sqlite
+
+SELECT 2
+FROM t
+
+-- name: b
+-- dialect: postgres
+SELECT 3
+-- name: empty
+`), "q.sql")
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	require.Nil(t, Export(&buf, dd, ExportOptions{}))
+	assert.Equal(t, `-- name: b
+-- tags: x
+SELECT 1
+
+-- name: a
+-- dialect: sqlite
+SELECT 2
+FROM t
+
+-- name: b
+-- dialect: postgres
+SELECT 3
+
+-- name: empty
+`, buf.String())
+
+	// exported files parse into the same definitions
+	edd, err := ParseDefinitions(strings.NewReader(buf.String()), "q.sql")
+	require.Nil(t, err)
+	require.Len(t, edd, len(dd))
+
+	for i := range dd {
+		assert.Equal(t, dd[i].Name, edd[i].Name)
+		assert.Equal(t, dd[i].Annotations, edd[i].Annotations)
+	}
+
+	buf.Reset()
+	require.Nil(t, Export(&buf, dd, ExportOptions{Sort: true}))
+	assert.Equal(t, `-- name: a
+-- dialect: sqlite
+SELECT 2
+FROM t
+
+-- name: b
+-- tags: x
+SELECT 1
+
+-- name: b
+-- dialect: postgres
+SELECT 3
+
+-- name: empty
+`, buf.String())
+	assert.Equal(t, "b", dd[0].Name)
+}
+
+func TestDotSqlxExport(t *testing.T) {
+	r := NewRegistry()
+	require.Nil(t, r.Add("b", "SELECT 2", Annotations{{Key: "owner", Value: "team"}}))
+	require.Nil(t, r.Add("a", "-- tags: x\nSELECT 1", nil))
+
+	var buf bytes.Buffer
+	require.Nil(t, WrapRegistry(r).Export(&buf))
+	assert.Equal(t, "-- name: a\n-- tags: x\nSELECT 1\n\n-- name: b\n-- owner: team\nSELECT 2\n", buf.String())
+
+	dot := newVariantsDot(t, dialectQueries)
+	assert.Equal(t, []Definition{
+		{Name: "select_now", SQL: "SELECT CURRENT_TIMESTAMP"},
+		{
+			Name:        "select_now",
+			SQL:         "-- dialect: postgres\nSELECT CAST(now() AS TEXT)",
+			Annotations: Annotations{{Key: "dialect", Value: "postgres"}},
+		},
+		{
+			Name:        "select_now",
+			SQL:         "-- dialect: sqlite\nSELECT datetime('now')",
+			Annotations: Annotations{{Key: "dialect", Value: "sqlite"}},
+		},
+		{Name: "select_nr", SQL: "SELECT nr FROM numbers"},
+		{
+			Name:        "select_pg",
+			SQL:         "-- dialect: postgres\nSELECT 1",
+			Annotations: Annotations{{Key: "dialect", Value: "postgres"}},
+		},
+	}, dot.Definitions())
+}
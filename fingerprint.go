@@ -0,0 +1,197 @@
+package dotsqlx
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Fingerprint returns a stable hash of the query that only changes when
+// its meaning may change: annotations, comments, whitespace and the case
+// of keywords are ignored.
+func Fingerprint(query string) string {
+	n := strings.Join(stripAnnotations(Normalize(query, NormalizeOptions{KeywordCase: UpperCase})), "\n")
+	sum := sha256.Sum256([]byte(n))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Fingerprint returns the fingerprint of the query.
+func (q Query) Fingerprint() string {
+	return Fingerprint(q.SQL)
+}
+
+// Fingerprint returns the fingerprint of dotsql named query, or of its
+// dialect-neutral variant.
+func (d DotSqlx) Fingerprint(name string) (string, error) {
+	query, err := d.resolve("", name)
+	if err != nil {
+		return "", err
+	}
+
+	return Fingerprint(query), nil
+}
+
+// Manifest holds query fingerprints keyed by query name. Dialect-specific
+// variants are keyed by the query name followed by `@` and the dialect.
+type Manifest map[string]string
+
+// Manifest returns the fingerprints of all loaded queries.
+func (d DotSqlx) Manifest() Manifest {
+	m := make(Manifest)
+	for _, def := range d.Definitions() {
+		key := def.Name
+		if dialect := def.Annotations.Get("dialect"); dialect != "" {
+			key += "@" + dialect
+		}
+
+		m[key] = Fingerprint(def.SQL)
+	}
+
+	return m
+}
+
+// Checksum returns the checksum of the loaded query set, which changes
+// whenever a query is added, removed or its fingerprint changes.
+func (d DotSqlx) Checksum() string {
+	return d.Manifest().Checksum()
+}
+
+// Verify compares the loaded query set with a manifest, e.g. one committed
+// along with the query files. A *ManifestError is returned if they differ.
+func (d DotSqlx) Verify(m Manifest) error {
+	return m.Diff(d.Manifest())
+}
+
+// keys returns the sorted manifest keys.
+func (m Manifest) keys() []string {
+	kk := make([]string, 0, len(m))
+	for k := range m {
+		kk = append(kk, k)
+	}
+
+	sort.Strings(kk)
+
+	return kk
+}
+
+// Checksum returns a hash of all manifest entries.
+func (m Manifest) Checksum() string {
+	h := sha256.New()
+	for _, k := range m.keys() {
+		fmt.Fprintf(h, "%s  %s\n", m[k], k)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// WriteTo writes the manifest to w, one `<fingerprint>  <name>` line per
+// entry, sorted by name.
+func (m Manifest) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, k := range m.keys() {
+		n, err := fmt.Fprintf(w, "%s  %s\n", m[k], k)
+		total += int64(n)
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// ReadManifest reads a manifest written by WriteTo. Empty lines and lines
+// starting with `#` are ignored.
+func ReadManifest(r io.Reader) (Manifest, error) {
+	m := make(Manifest)
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		ff := strings.Fields(l)
+		if len(ff) != 2 {
+			return nil, fmt.Errorf("dotsqlx: manifest line %d: invalid entry", line)
+		}
+
+		m[ff[1]] = ff[0]
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ManifestError describes the differences between an expected and an
+// actual manifest.
+type ManifestError struct {
+	// Missing holds the entries of the expected manifest that are not
+	// loaded.
+	Missing []string
+
+	// Unexpected holds the loaded entries that are not in the expected
+	// manifest.
+	Unexpected []string
+
+	// Changed holds the entries whose fingerprints differ.
+	Changed []string
+}
+
+// Error lists all differences.
+func (e *ManifestError) Error() string {
+	var b strings.Builder
+	b.WriteString("dotsqlx: query set does not match the manifest")
+
+	for _, d := range []struct {
+		kind string
+		kk   []string
+	}{
+		{"missing", e.Missing},
+		{"unexpected", e.Unexpected},
+		{"changed", e.Changed},
+	} {
+		for _, k := range d.kk {
+			fmt.Fprintf(&b, "\n\t%s: %s", d.kind, k)
+		}
+	}
+
+	return b.String()
+}
+
+// Diff compares the manifest, holding the expected entries, with the
+// actual one. A *ManifestError is returned if they differ.
+func (m Manifest) Diff(actual Manifest) error {
+	var e ManifestError
+
+	for _, k := range m.keys() {
+		fp, ok := actual[k]
+		switch {
+		case !ok:
+			e.Missing = append(e.Missing, k)
+		case fp != m[k]:
+			e.Changed = append(e.Changed, k)
+		}
+	}
+
+	for _, k := range actual.keys() {
+		if _, ok := m[k]; !ok {
+			e.Unexpected = append(e.Unexpected, k)
+		}
+	}
+
+	if len(e.Missing)+len(e.Unexpected)+len(e.Changed) > 0 {
+		return &e
+	}
+
+	return nil
+}
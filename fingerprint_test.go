@@ -0,0 +1,79 @@
+package dotsqlx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	fp := Fingerprint("SELECT nr FROM numbers WHERE nr = 'a  b'")
+	assert.Len(t, fp, 64)
+
+	assert.Equal(t, fp, Fingerprint("-- tags: x\nselect nr -- number\n  from numbers\nwhere nr = 'a  b'"))
+	assert.NotEqual(t, fp, Fingerprint("SELECT nr FROM numbers WHERE nr = 'a b'"))
+	assert.NotEqual(t, fp, Fingerprint("SELECT nr FROM numbers WHERE nr = 'A  B'"))
+
+	q := Query{Name: "a", SQL: "SELECT nr FROM numbers WHERE nr = 'a  b'"}
+	assert.Equal(t, fp, q.Fingerprint())
+}
+
+func TestManifest(t *testing.T) {
+	tr := NewTracker()
+	dot := newVariantsDot(t, dialectQueries).WithTracker(tr)
+
+	fp, err := dot.Fingerprint("select_nr")
+	require.Nil(t, err)
+	assert.Equal(t, Fingerprint("SELECT nr FROM numbers"), fp)
+	assert.Empty(t, tr.Executed())
+
+	_, err = dot.Fingerprint("missing")
+	assert.NotNil(t, err)
+
+	m := dot.Manifest()
+	assert.Equal(t, Manifest{
+		"select_now":          Fingerprint("SELECT CURRENT_TIMESTAMP"),
+		"select_now@postgres": Fingerprint("SELECT CAST(now() AS TEXT)"),
+		"select_now@sqlite":   Fingerprint("SELECT datetime('now')"),
+		"select_nr":           fp,
+		"select_pg@postgres":  Fingerprint("SELECT 1"),
+	}, m)
+	assert.Equal(t, m.Checksum(), dot.Checksum())
+	assert.Nil(t, dot.Verify(m))
+
+	var buf bytes.Buffer
+	_, err = m.WriteTo(&buf)
+	require.Nil(t, err)
+	assert.True(t, strings.HasPrefix(buf.String(), m["select_now"]+"  select_now\n"))
+
+	rm, err := ReadManifest(strings.NewReader("# committed manifest\n\n" + buf.String()))
+	require.Nil(t, err)
+	assert.Equal(t, m, rm)
+
+	_, err = ReadManifest(strings.NewReader("abc\n"))
+	assert.EqualError(t, err, "dotsqlx: manifest line 1: invalid entry")
+
+	other := newDot(t, `
+-- name: select_now
+SELECT CURRENT_TIMESTAMP
+
+-- name: select_nr
+SELECT nr FROM numbers ORDER BY nr
+
+-- name: select_new
+SELECT 1
+`)
+	assert.NotEqual(t, m.Checksum(), other.Checksum())
+
+	err = other.Verify(m)
+
+	merr, ok := err.(*ManifestError)
+	require.True(t, ok)
+	assert.Equal(t, []string{"select_now@postgres", "select_now@sqlite", "select_pg@postgres"}, merr.Missing)
+	assert.Equal(t, []string{"select_new"}, merr.Unexpected)
+	assert.Equal(t, []string{"select_nr"}, merr.Changed)
+	assert.Equal(t, "dotsqlx: query set does not match the manifest\n\tmissing: select_now@postgres\n\tmissing: select_now@sqlite\n\tmissing: select_pg@postgres\n\tunexpected: select_new\n\tchanged: select_nr", err.Error())
+}
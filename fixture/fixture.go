@@ -0,0 +1,287 @@
+// Package fixture loads test data with named insert queries. Fixture files
+// are YAML or JSON documents that map insert query names to the rows they
+// are executed with:
+//
+//	insert_user:
+//	  - id: 1
+//	    name: admin
+//	insert_post:
+//	  - id: 1
+//	    user_id: 1
+//	    body: Hello
+//
+// Every row is passed to DotSqlx.NamedExecContext, so the queries use
+// named bindvars:
+//
+//	-- name: insert_post
+//	-- depends: insert_user
+//	INSERT INTO posts (id, user_id, body) VALUES (:id, :user_id, :body)
+//
+// The `-- depends:` annotation lists the comma separated insert queries
+// that have to be executed first, e.g. because of foreign keys.
+package fixture
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swithek/dotsqlx"
+	"gopkg.in/yaml.v3"
+)
+
+// tableRe matches the table of a normalised insert query.
+var tableRe = regexp.MustCompile(`(?im)^INSERT\s+(?:OR\s+\w+\s+)?INTO\s+([^\s(]+)`)
+
+// Set holds the rows of a single insert query.
+type Set struct {
+	Query string
+	Rows  []map[string]interface{}
+}
+
+// Fixtures holds the fixtures of files, sorted in dependency order.
+type Fixtures struct {
+	dot    *dotsqlx.DotSqlx
+	sets   []Set
+	tables []string
+}
+
+// New reads the fixture files, which must have the .yaml, .yml or .json
+// extension, and creates a new Fixtures instance. Rows of a query found in
+// several files are appended in the order of the files. All queries must
+// be inserts loaded by d.
+func New(d *dotsqlx.DotSqlx, files ...string) (*Fixtures, error) {
+	var (
+		sets  = make(map[string]*Set)
+		names []string
+	)
+
+	for _, file := range files {
+		ss, err := read(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range ss {
+			if set, ok := sets[s.Query]; ok {
+				set.Rows = append(set.Rows, s.Rows...)
+				continue
+			}
+
+			s := s
+			sets[s.Query] = &s
+			names = append(names, s.Query)
+		}
+	}
+
+	deps := make(map[string][]string)
+	tables := make(map[string]string)
+
+	for _, def := range d.Definitions() {
+		if _, ok := sets[def.Name]; !ok {
+			continue
+		}
+
+		for _, v := range def.Annotations.Values("depends") {
+			for _, dep := range strings.Split(v, ",") {
+				if dep = strings.TrimSpace(dep); dep != "" {
+					deps[def.Name] = append(deps[def.Name], dep)
+				}
+			}
+		}
+
+		if _, ok := tables[def.Name]; ok {
+			continue
+		}
+
+		m := tableRe.FindStringSubmatch(dotsqlx.Normalize(def.SQL, dotsqlx.NormalizeOptions{KeywordCase: dotsqlx.UpperCase}))
+		if m == nil {
+			return nil, fmt.Errorf("dotsqlx: fixture query '%s' is not an insert", def.Name)
+		}
+
+		tables[def.Name] = m[1]
+	}
+
+	f := &Fixtures{dot: d}
+	state := make(map[string]int) // 1: visiting, 2: done
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("dotsqlx: fixture dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+
+		state[name] = 1
+
+		for _, dep := range deps[name] {
+			// dependencies without fixtures are already satisfied
+			if _, ok := sets[dep]; !ok {
+				continue
+			}
+
+			if err := visit(dep, append(path[:len(path):len(path)], name)); err != nil {
+				return err
+			}
+		}
+
+		state[name] = 2
+		f.sets = append(f.sets, *sets[name])
+
+		if !contains(f.tables, tables[name]) {
+			f.tables = append(f.tables, tables[name])
+		}
+
+		return nil
+	}
+
+	for _, name := range names {
+		if _, ok := tables[name]; !ok {
+			return nil, &dotsqlx.QueryError{Name: name, Err: dotsqlx.ErrNotFound}
+		}
+
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// read reads the sets of a fixture file in their document order. JSON
+// documents are read with the YAML parser, which accepts them as well.
+func read(file string) ([]Set, error) {
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return nil, fmt.Errorf("dotsqlx: %s: unsupported fixture file", file)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("dotsqlx: %s: %v", file, err)
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("dotsqlx: %s:%d: fixtures must be a mapping", file, root.Line)
+	}
+
+	var ss []Set
+	for i := 0; i < len(root.Content); i += 2 {
+		k, v := root.Content[i], root.Content[i+1]
+
+		s := Set{Query: k.Value}
+		if err := v.Decode(&s.Rows); err != nil {
+			return nil, fmt.Errorf("dotsqlx: %s:%d: %s: rows must be a list of mappings", file, k.Line, k.Value)
+		}
+
+		ss = append(ss, s)
+	}
+
+	return ss, nil
+}
+
+// Sets returns the fixtures in the order they are loaded in.
+func (f *Fixtures) Sets() []Set {
+	return append([]Set(nil), f.sets...)
+}
+
+// Tables returns the tables the fixtures are inserted into, in dependency
+// order.
+func (f *Fixtures) Tables() []string {
+	return append([]string(nil), f.tables...)
+}
+
+// Load inserts all rows in dependency order inside one transaction.
+func (f *Fixtures) Load(ctx context.Context, db *sqlx.DB) error {
+	return transact(ctx, db, func(tx *sqlx.Tx) error {
+		for _, s := range f.sets {
+			for i, row := range s.Rows {
+				if _, err := f.dot.NamedExecContext(ctx, tx, s.Query, row); err != nil {
+					return fmt.Errorf("dotsqlx: fixture %s[%d]: %v", s.Query, i, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Reset deletes all rows of the fixture tables, dependants first, inside
+// one transaction.
+func (f *Fixtures) Reset(ctx context.Context, db *sqlx.DB) error {
+	return transact(ctx, db, func(tx *sqlx.Tx) error {
+		for i := len(f.tables) - 1; i >= 0; i-- {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+f.tables[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Setup resets the fixture tables and loads the fixtures for a test. The
+// returned function resets the tables again and is meant to be deferred:
+//
+//	defer f.Setup(t, db)()
+func (f *Fixtures) Setup(t testing.TB, db *sqlx.DB) func() {
+	t.Helper()
+
+	ctx := context.Background()
+	if err := f.Reset(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Load(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		if err := f.Reset(ctx, db); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// transact calls fn in a transaction that is committed if fn succeeds.
+func transact(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,167 @@
+package fixture
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/dotsqlx"
+)
+
+func newDot(t *testing.T) *dotsqlx.DotSqlx {
+	dot, err := dotsqlx.Load("testdata/queries.sql")
+	require.Nil(t, err)
+
+	return dot
+}
+
+func newDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+
+	db.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	db.MustExec("CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER, body TEXT)")
+	db.MustExec("CREATE TABLE comments (post_id INTEGER, user_id INTEGER, body TEXT)")
+
+	return db
+}
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotsqlx")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		require.Nil(t, ioutil.WriteFile(file, []byte(content), 0644))
+
+		return file
+	}
+
+	cc := map[string]struct {
+		Files []string
+		Err   string
+	}{
+		"Unsupported file": {
+			Files: []string{write("users.sql", "")},
+			Err:   "unsupported fixture file",
+		},
+		"Missing file": {
+			Files: []string{filepath.Join(dir, "missing.yaml")},
+			Err:   "no such file",
+		},
+		"Invalid document": {
+			Files: []string{write("invalid.yaml", "insert_user: [")},
+			Err:   "invalid.yaml",
+		},
+		"Not a mapping": {
+			Files: []string{write("list.yaml", "- insert_user")},
+			Err:   "list.yaml:1: fixtures must be a mapping",
+		},
+		"Invalid rows": {
+			Files: []string{write("rows.json", `{"insert_user": {"id": 1}}`)},
+			Err:   "rows.json:1: insert_user: rows must be a list of mappings",
+		},
+		"Unknown query": {
+			Files: []string{write("unknown.yaml", "insert_group: []")},
+			Err:   "insert_group: dotsqlx: query not found",
+		},
+		"Not an insert": {
+			Files: []string{write("select.yaml", "select_users: []")},
+			Err:   "fixture query 'select_users' is not an insert",
+		},
+		"Dependency cycle": {
+			Files: []string{write("cycle.yaml", "insert_a: []\ninsert_b: []")},
+			Err:   "fixture dependency cycle: insert_a -> insert_b -> insert_a",
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			_, err := New(newDot(t), c.Files...)
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), c.Err)
+		})
+	}
+
+	// dependencies are loaded first, whatever the file order
+	f, err := New(newDot(t), "testdata/posts.json", "testdata/users.yaml", write("more.yaml", "insert_user:\n  - id: 3\n    name: bot"))
+	require.Nil(t, err)
+
+	ss := f.Sets()
+	require.Len(t, ss, 3)
+	assert.Equal(t, "insert_user", ss[0].Query)
+	assert.Len(t, ss[0].Rows, 3)
+	assert.Equal(t, "insert_post", ss[1].Query)
+	assert.Equal(t, "insert_comment", ss[2].Query)
+	assert.Equal(t, []string{"users", "posts", "comments"}, f.Tables())
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer db.Close()
+
+	f, err := New(newDot(t), "testdata/users.yaml", "testdata/posts.json")
+	require.Nil(t, err)
+
+	require.Nil(t, f.Load(ctx, db))
+
+	var names []string
+	require.Nil(t, db.Select(&names, "SELECT name FROM users ORDER BY id"))
+	assert.Equal(t, []string{"admin", "guest"}, names)
+
+	var count int
+	require.Nil(t, db.Get(&count, "SELECT COUNT(*) FROM posts"))
+	assert.Equal(t, 2, count)
+
+	// duplicate rows fail and are rolled back
+	db.MustExec("DELETE FROM comments")
+
+	err = f.Load(ctx, db)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "fixture insert_user[0]")
+
+	require.Nil(t, db.Get(&count, "SELECT COUNT(*) FROM comments"))
+	assert.Zero(t, count)
+
+	require.Nil(t, f.Reset(ctx, db))
+
+	for _, table := range f.Tables() {
+		require.Nil(t, db.Get(&count, "SELECT COUNT(*) FROM "+table))
+		assert.Zero(t, count)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	db := newDB(t)
+	defer db.Close()
+
+	f, err := New(newDot(t), "testdata/users.yaml")
+	require.Nil(t, err)
+
+	// leftovers of previous tests are removed
+	db.MustExec("INSERT INTO users (id, name) VALUES (1, 'old')")
+
+	var count int
+	for i := 0; i < 2; i++ {
+		t.Run("Setup", func(t *testing.T) {
+			defer f.Setup(t, db)()
+
+			require.Nil(t, db.Get(&count, "SELECT COUNT(*) FROM users"))
+			assert.Equal(t, 2, count)
+		})
+	}
+
+	require.Nil(t, db.Get(&count, "SELECT COUNT(*) FROM users"))
+	assert.Zero(t, count)
+}
@@ -0,0 +1,22 @@
+-- name: insert_user
+INSERT INTO users (id, name) VALUES (:id, :name)
+
+-- name: insert_post
+-- depends: insert_user
+INSERT INTO posts (id, user_id, body) VALUES (:id, :user_id, :body)
+
+-- name: insert_comment
+-- depends: insert_post, insert_user
+insert or ignore into comments (post_id, user_id, body)
+VALUES (:post_id, :user_id, :body)
+
+-- name: select_users
+SELECT * FROM users
+
+-- name: insert_a
+-- depends: insert_b
+INSERT INTO a (id) VALUES (:id)
+
+-- name: insert_b
+-- depends: insert_a
+INSERT INTO b (id) VALUES (:id)
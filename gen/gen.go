@@ -0,0 +1,423 @@
+// Package gen generates typed Go methods from dotsql named queries and
+// their annotations:
+//
+//	-- name: select_users
+//	-- param: minAge int
+//	-- returns: many User
+//	SELECT * FROM users WHERE age >= ?
+//
+// Besides the queries struct, an interface with one method per query and
+// an in-memory fake implementing it are generated, so that code depending
+// on the queries can be tested without a database.
+//
+// Supported `-- returns:` annotations are `many <type>`, which uses
+// Select, `one <type>`, which uses Get, and `exec`, which uses Exec and is
+// the default. Every `-- param:` annotation adds a positional argument and
+// `-- import:` annotations add import paths required by the used types.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/swithek/dotsqlx"
+)
+
+// Kinds of query results.
+const (
+	ReturnsExec = "exec"
+	ReturnsOne  = "one"
+	ReturnsMany = "many"
+)
+
+// identRe matches a Go identifier.
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reserved holds identifiers used by the generated methods that cannot be
+// used as param names.
+var reserved = map[string]bool{
+	"ctx":     true,
+	"db":      true,
+	"q":       true,
+	"f":       true,
+	"fn":      true,
+	"res":     true,
+	"err":     true,
+	"context": true,
+	"sql":     true,
+	"sync":    true,
+	"dotsqlx": true,
+}
+
+// Param is a positional argument of a query.
+type Param struct {
+	Name string
+	Type string
+}
+
+// Field returns the exported field name of the param.
+func (p Param) Field() string {
+	return strings.ToUpper(p.Name[:1]) + p.Name[1:]
+}
+
+// Query holds everything needed to generate a typed method of a dotsql
+// named query.
+type Query struct {
+	// Name is the dotsql query name.
+	Name string
+
+	// Const is the name of the generated query name constant.
+	Const string
+
+	// Method is the name of the generated method.
+	Method string
+
+	// Returns is the kind of the query result.
+	Returns string
+
+	// Type is the Go type of a single result row. It is empty for exec
+	// queries.
+	Type string
+
+	// Params are the positional query arguments.
+	Params []Param
+
+	// Imports are the import paths required by Type and Params.
+	Imports []string
+}
+
+// Config holds the generator options.
+type Config struct {
+	// Package is the name of the generated package.
+	Package string
+
+	// Type is the name of the generated queries struct. Defaults to
+	// "Queries".
+	Type string
+
+	// Interface is the name of the generated queries interface. Defaults
+	// to "Querier".
+	Interface string
+
+	// Fake is the name of the generated fake implementation. Defaults to
+	// "Fake" followed by Type.
+	Fake string
+}
+
+// Parse converts every query of the map into a Query, sorted by name. An
+// error is returned if two query names, e.g. "users_get" and "users.get",
+// map to the same method name.
+func Parse(qm map[string]string) ([]Query, error) {
+	names := make([]string, 0, len(qm))
+	for name := range qm {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	qq := make([]Query, 0, len(names))
+	methods := make(map[string]string, len(names))
+	for _, name := range names {
+		q, err := ParseQuery(name, qm[name])
+		if err != nil {
+			return nil, err
+		}
+
+		if prev, ok := methods[q.Method]; ok {
+			return nil, fmt.Errorf("gen: %s: method name %s is already used by %s", name, q.Method, prev)
+		}
+
+		methods[q.Method] = name
+		qq = append(qq, q)
+	}
+
+	return qq, nil
+}
+
+// ParseQuery converts a single dotsql named query into a Query.
+func ParseQuery(name, query string) (Query, error) {
+	method := Ident(name)
+	if method == "" {
+		return Query{}, fmt.Errorf("gen: %s: invalid query name", name)
+	}
+
+	aa := dotsqlx.ParseAnnotations(query)
+	q := Query{
+		Name:    name,
+		Const:   "Query" + method,
+		Method:  method,
+		Returns: ReturnsExec,
+		Imports: aa.Values("import"),
+	}
+
+	if r := strings.Fields(aa.Get("returns")); len(r) > 0 {
+		q.Returns = r[0]
+
+		switch {
+		case q.Returns == ReturnsExec && len(r) == 1:
+		case (q.Returns == ReturnsOne || q.Returns == ReturnsMany) && len(r) == 2:
+			q.Type = r[1]
+		default:
+			return Query{}, fmt.Errorf("gen: %s: invalid returns annotation %q", name, aa.Get("returns"))
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, imp := range q.Imports {
+		used[imp[strings.LastIndex(imp, "/")+1:]] = true
+	}
+
+	for _, v := range aa.Values("param") {
+		p := strings.Fields(v)
+		if len(p) != 2 || !validParam(p[0]) || used[p[0]] {
+			return Query{}, fmt.Errorf("gen: %s: invalid param annotation %q", name, v)
+		}
+
+		param := Param{Name: p[0], Type: p[1]}
+		if used[param.Field()] {
+			return Query{}, fmt.Errorf("gen: %s: duplicate param annotation %q", name, v)
+		}
+
+		used[param.Name], used[param.Field()] = true, true
+		q.Params = append(q.Params, param)
+	}
+
+	return q, nil
+}
+
+// validParam reports whether the name can be used as a param name, i.e. it
+// is a Go identifier that is neither a keyword, a predeclared identifier nor
+// used by the generated methods.
+func validParam(name string) bool {
+	return identRe.MatchString(name) && !token.Lookup(name).IsKeyword() &&
+		types.Universe.Lookup(name) == nil && !reserved[name]
+}
+
+// Ident converts a query name, e.g. "select_users" or "users.select", into
+// an exported Go identifier. An empty string is returned if the name
+// contains no letters or digits.
+func Ident(name string) string {
+	var b strings.Builder
+	upper := true
+
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+
+		b.WriteRune(r)
+	}
+
+	id := b.String()
+	if id != "" && unicode.IsDigit(rune(id[0])) {
+		id = "Q" + id
+	}
+
+	return id
+}
+
+// Generate writes formatted Go source of the typed queries struct to w.
+func Generate(w io.Writer, cfg Config, qq []Query) error {
+	if cfg.Type == "" {
+		cfg.Type = "Queries"
+	}
+
+	if cfg.Interface == "" {
+		cfg.Interface = "Querier"
+	}
+
+	if cfg.Fake == "" {
+		cfg.Fake = "Fake" + cfg.Type
+	}
+
+	imports := map[string]bool{
+		"context":                    true,
+		"sync":                       true,
+		"github.com/swithek/dotsqlx": true,
+	}
+
+	for _, q := range qq {
+		if q.Returns == ReturnsExec {
+			imports["database/sql"] = true
+		}
+
+		for _, imp := range q.Imports {
+			imports[imp] = true
+		}
+	}
+
+	data := struct {
+		Config
+		StdImports []string
+		Imports    []string
+		Queries    []Query
+	}{
+		Config:  cfg,
+		Queries: qq,
+	}
+
+	for imp := range imports {
+		if strings.Contains(strings.SplitN(imp, "/", 2)[0], ".") {
+			data.Imports = append(data.Imports, imp)
+			continue
+		}
+
+		data.StdImports = append(data.StdImports, imp)
+	}
+
+	sort.Strings(data.StdImports)
+	sort.Strings(data.Imports)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+var tmpl = template.Must(template.New("gen").Parse(`// Code generated by dotsqlx-gen; DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+{{- range .StdImports }}
+	"{{ . }}"
+{{- end }}
+{{ range .Imports }}
+	"{{ . }}"
+{{- end }}
+)
+
+// Names of the loaded queries.
+const (
+{{- range .Queries }}
+	{{ .Const }} = {{ printf "%q" .Name }}
+{{- end }}
+)
+
+{{- define "params" }}ctx context.Context, db dotsqlx.ExtContext{{ range .Params }}, {{ .Name }} {{ .Type }}{{ end }}{{ end }}
+
+{{- define "results" }}
+{{- if eq .Returns "many" }}[]{{ .Type }}{{ else if eq .Returns "one" }}{{ .Type }}{{ else }}sql.Result{{ end }}, error
+{{- end }}
+
+// {{ .Interface }} is implemented by {{ .Type }} and {{ .Fake }}.
+type {{ .Interface }} interface {
+{{- range .Queries }}
+	{{ .Method }}({{ template "params" . }}) ({{ template "results" . }})
+{{- end }}
+}
+
+var (
+	_ {{ .Interface }} = &{{ .Type }}{}
+	_ {{ .Interface }} = &{{ .Fake }}{}
+)
+
+// {{ .Type }} provides typed methods executing the loaded queries.
+type {{ .Type }} struct {
+	dot *dotsqlx.DotSqlx
+}
+
+// New{{ .Type }} creates a new {{ .Type }} instance.
+func New{{ .Type }}(dot *dotsqlx.DotSqlx) *{{ .Type }} {
+	return &{{ .Type }}{dot: dot}
+}
+{{ range .Queries }}
+// {{ .Method }} executes the {{ .Name }} query.
+func (q *{{ $.Type }}) {{ .Method }}({{ template "params" . }}) ({{ template "results" . }}) {
+{{- if eq .Returns "many" }}
+	var res []{{ .Type }}
+	err := q.dot.SelectContext(ctx, db, &res, {{ .Const }}{{ range .Params }}, {{ .Name }}{{ end }})
+	return res, err
+{{- else if eq .Returns "one" }}
+	var res {{ .Type }}
+	err := q.dot.GetContext(ctx, db, &res, {{ .Const }}{{ range .Params }}, {{ .Name }}{{ end }})
+	return res, err
+{{- else }}
+	return q.dot.ExecContext(ctx, db, {{ .Const }}{{ range .Params }}, {{ .Name }}{{ end }})
+{{- end }}
+}
+{{ end }}
+// {{ .Fake }} is an in-memory {{ .Interface }} implementation. Each method
+// records its call and returns the results of the corresponding function
+// field or zero values if the field is nil.
+type {{ .Fake }} struct {
+	mu sync.Mutex
+{{ range .Queries }}
+	// {{ .Method }}Func stubs the {{ .Name }} query.
+	{{ .Method }}Func func({{ template "params" . }}) ({{ template "results" . }})
+{{ end }}
+	calls struct {
+{{- range .Queries }}
+		{{ .Method }} []struct {
+{{- range .Params }}
+			{{ .Field }} {{ .Type }}
+{{- end }}
+		}
+{{- end }}
+	}
+}
+{{ range .Queries }}
+// {{ .Method }} calls {{ .Method }}Func.
+func (f *{{ $.Fake }}) {{ .Method }}({{ template "params" . }}) ({{ template "results" . }}) {
+	f.mu.Lock()
+	f.calls.{{ .Method }} = append(f.calls.{{ .Method }}, struct {
+{{- range .Params }}
+		{{ .Field }} {{ .Type }}
+{{- end }}
+	}{
+{{- range .Params }}
+		{{ .Field }}: {{ .Name }},
+{{- end }}
+	})
+	fn := f.{{ .Method }}Func
+	f.mu.Unlock()
+
+	if fn == nil {
+{{- if eq .Returns "many" }}
+		return nil, nil
+{{- else if eq .Returns "one" }}
+		var res {{ .Type }}
+		return res, nil
+{{- else }}
+		return nil, nil
+{{- end }}
+	}
+
+	return fn(ctx, db{{ range .Params }}, {{ .Name }}{{ end }})
+}
+
+// {{ .Method }}Calls returns all calls made to {{ .Method }}.
+func (f *{{ $.Fake }}) {{ .Method }}Calls() []struct {
+{{- range .Params }}
+	{{ .Field }} {{ .Type }}
+{{- end }}
+} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls.{{ .Method }}
+}
+{{ end }}`))
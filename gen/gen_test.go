@@ -0,0 +1,140 @@
+package gen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"testing"
+
+	"github.com/qustavo/dotsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdent(t *testing.T) {
+	assert.Equal(t, "SelectUsers", Ident("select_users"))
+	assert.Equal(t, "UsersGetByID", Ident("users.getByID"))
+	assert.Equal(t, "Q1Up", Ident("1-up"))
+	assert.Zero(t, Ident("--"))
+}
+
+func TestParamField(t *testing.T) {
+	assert.Equal(t, "MinAge", Param{Name: "minAge"}.Field())
+}
+
+func TestParseQuery(t *testing.T) {
+	// invalid name
+	_, err := ParseQuery("..", "SELECT 1")
+	assert.NotNil(t, err)
+
+	// invalid returns annotation
+	_, err = ParseQuery("q", "-- returns: many\nSELECT 1")
+	assert.NotNil(t, err)
+
+	_, err = ParseQuery("q", "-- returns: some User\nSELECT 1")
+	assert.NotNil(t, err)
+
+	// invalid param annotation
+	_, err = ParseQuery("q", "-- param: id\nSELECT 1")
+	assert.NotNil(t, err)
+
+	for _, p := range []string{"ctx", "f", "fn", "type", "string", "time"} {
+		_, err = ParseQuery("q", "-- import: time\n-- param: "+p+" int\nSELECT 1")
+		assert.NotNil(t, err, p)
+	}
+
+	_, err = ParseQuery("q", "-- param: id int\n-- param: id int\nSELECT 1")
+	assert.NotNil(t, err)
+
+	_, err = ParseQuery("q", "-- param: Id int\n-- param: id int\nSELECT 1")
+	assert.NotNil(t, err)
+
+	// defaults
+	q, err := ParseQuery("delete_users", "DELETE FROM users")
+	require.Nil(t, err)
+	assert.Equal(t, Query{
+		Name:    "delete_users",
+		Const:   "QueryDeleteUsers",
+		Method:  "DeleteUsers",
+		Returns: ReturnsExec,
+	}, q)
+
+	// annotated
+	q, err = ParseQuery("get_user", "-- import: time\n-- param: id int64\n-- param: since time.Time\n-- returns: one *User\nSELECT 1")
+	require.Nil(t, err)
+	assert.Equal(t, Query{
+		Name:    "get_user",
+		Const:   "QueryGetUser",
+		Method:  "GetUser",
+		Returns: ReturnsOne,
+		Type:    "*User",
+		Params:  []Param{{Name: "id", Type: "int64"}, {Name: "since", Type: "time.Time"}},
+		Imports: []string{"time"},
+	}, q)
+}
+
+func TestParse(t *testing.T) {
+	// invalid query
+	qq, err := Parse(map[string]string{"a": "SELECT 1", "b": "-- returns: one\nSELECT 1"})
+	assert.Nil(t, qq)
+	assert.NotNil(t, err)
+
+	// colliding method names
+	qq, err = Parse(map[string]string{"users_get": "SELECT 1", "users.get": "SELECT 1"})
+	assert.Nil(t, qq)
+	assert.NotNil(t, err)
+
+	// successful call
+	qq, err = Parse(map[string]string{"b": "SELECT 1", "a": "SELECT 1"})
+	require.Nil(t, err)
+	require.Len(t, qq, 2)
+	assert.Equal(t, "a", qq[0].Name)
+	assert.Equal(t, "b", qq[1].Name)
+}
+
+func TestGenerate(t *testing.T) {
+	dot, err := dotsql.LoadFromFile("testdata/queries.sql")
+	require.Nil(t, err)
+
+	qq, err := Parse(dot.QueryMap())
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	require.Nil(t, Generate(&buf, Config{Package: "store"}, qq))
+
+	golden, err := ioutil.ReadFile("testdata/queries.go.golden")
+	require.Nil(t, err)
+	assert.Equal(t, string(golden), buf.String())
+
+	// invalid source
+	err = Generate(&buf, Config{Package: "1store"}, qq)
+	assert.NotNil(t, err)
+
+	// quoted name
+	q, err := ParseQuery(`select"user\s`, "SELECT 1")
+	require.Nil(t, err)
+
+	buf.Reset()
+	require.Nil(t, Generate(&buf, Config{Package: "store"}, []Query{q}))
+	assert.Contains(t, buf.String(), `SelectUserS = "select\"user\\s"`)
+}
+
+func TestGenerateTypeCheck(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/queries.go.golden")
+	require.Nil(t, err)
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "queries.go", src, 0)
+	require.Nil(t, err)
+
+	user, err := parser.ParseFile(fset, "user.go", "package store\n\ntype User struct {\n\tID   int64\n\tName string\n}\n", 0)
+	require.Nil(t, err)
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("store", fset, []*ast.File{f, user}, nil)
+	assert.Nil(t, err)
+}
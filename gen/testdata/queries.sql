@@ -0,0 +1,15 @@
+-- name: select_users
+-- param: minAge int
+-- returns: many User
+SELECT id, name FROM users WHERE age >= ?
+
+-- name: users.get
+-- import: time
+-- param: id int64
+-- param: since time.Time
+-- returns: one User
+SELECT id, name FROM users WHERE id = ? AND created_at > ?
+
+-- name: delete_user
+-- param: id int64
+DELETE FROM users WHERE id = ?
@@ -4,7 +4,9 @@ go 1.12
 
 require (
 	github.com/jmoiron/sqlx v1.2.0
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/qustavo/dotsql v1.1.0
 	github.com/stretchr/testify v1.5.1
 	google.golang.org/appengine v1.6.7 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,159 @@
+// Package golden provides a test helper that snapshots the SQL of all
+// named queries into golden files, so that changes to queries show up
+// explicitly in code review:
+//
+//	func TestQueries(t *testing.T) {
+//		dot, err := dotsqlx.Load("queries.sql")
+//		require.NoError(t, err)
+//
+//		golden.Assert(t, dot, golden.Options{Drivers: []string{"postgres", "sqlite3"}})
+//	}
+//
+// Golden files are written under testdata/golden when the tests are run
+// with the -golden.update flag and compared with the rendered queries
+// otherwise.
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/swithek/dotsqlx"
+)
+
+var update = flag.Bool("golden.update", false, "update golden files")
+
+// DefaultDir is the directory golden files are written to when none is
+// set.
+const DefaultDir = "testdata/golden"
+
+// Sample is a named set of template data.
+type Sample struct {
+	Name string
+	Data map[string]interface{}
+}
+
+// Options holds the options of Assert.
+type Options struct {
+	// Dir is the directory of the golden files. Defaults to DefaultDir.
+	Dir string
+
+	// Drivers are the names of the database drivers queries are rendered
+	// for, e.g. "postgres" or "sqlite3". Each driver gets its own golden
+	// file, holding the variants of queries for the driver's dialect,
+	// rebound to the driver's bindvar type. If empty, the queries are
+	// written as they are to default.sql.
+	Drivers []string
+
+	// Templates, if set, renders the queries listed in Samples, once per
+	// sample. It must use the same queries as the asserted DotSqlx.
+	Templates *dotsqlx.Templates
+
+	// Samples holds the template data of templated queries, keyed by query
+	// name.
+	Samples map[string][]Sample
+}
+
+// Assert renders all queries of d and compares them with the golden files,
+// or updates the files if the -golden.update flag is set.
+func Assert(t testing.TB, d *dotsqlx.DotSqlx, opts Options) {
+	t.Helper()
+
+	if opts.Dir == "" {
+		opts.Dir = DefaultDir
+	}
+
+	drivers := opts.Drivers
+	if len(drivers) == 0 {
+		drivers = []string{""}
+	}
+
+	for _, driver := range drivers {
+		got, err := Render(d, driver, opts)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		name := driver
+		if name == "" {
+			name = "default"
+		}
+
+		file := filepath.Join(opts.Dir, name+".sql")
+
+		if *update {
+			if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ioutil.WriteFile(file, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			continue
+		}
+
+		want, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Errorf("golden: %v; run the tests with -golden.update to create it", err)
+			continue
+		}
+
+		assert.Equal(t, string(want), string(got), "golden: %s is out of date; run the tests with -golden.update to update it", file)
+	}
+}
+
+// Render returns the dotsql file compared by Assert for the driver.
+func Render(d *dotsqlx.DotSqlx, driver string, opts Options) ([]byte, error) {
+	// rendering the queries must not mark them as executed
+	d = d.WithTracker(nil)
+
+	var dd []dotsqlx.Definition
+	for _, name := range d.Names() {
+		query, err := d.RawFor(driver, name)
+		if err != nil {
+			return nil, err
+		}
+
+		ss := opts.Samples[name]
+		if len(ss) == 0 || opts.Templates == nil {
+			ss = []Sample{{}}
+		}
+
+		for _, s := range ss {
+			q := query
+			if s.Data != nil || s.Name != "" {
+				if q, err = opts.Templates.RenderFor(driver, name, s.Data); err != nil {
+					return nil, err
+				}
+			}
+
+			if driver != "" {
+				if q, err = dotsqlx.RebindQuery(sqlx.BindType(driver), q); err != nil {
+					return nil, &dotsqlx.QueryError{Name: name, Err: err}
+				}
+			}
+
+			aa := dotsqlx.ParseAnnotations(q)
+			if s.Name != "" {
+				aa = append(aa[:len(aa):len(aa)], dotsqlx.Annotation{Key: "sample", Value: s.Name})
+			}
+
+			dd = append(dd, dotsqlx.Definition{Name: name, SQL: q, Annotations: aa})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := dotsqlx.Export(&buf, dd, dotsqlx.ExportOptions{}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,142 @@
+package golden
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/dotsqlx"
+)
+
+func newOptions(t *testing.T) (*dotsqlx.DotSqlx, Options) {
+	dot, err := dotsqlx.Load("testdata/queries.sql")
+	require.Nil(t, err)
+
+	return dot, Options{
+		Drivers: []string{"postgres", "sqlite3"},
+		Templates: dotsqlx.NewTemplates(dot, dotsqlx.TemplateOptions{
+			Idents: []string{"id", "name"},
+		}),
+		Samples: map[string][]Sample{
+			"select_users": {
+				{Name: "by id", Data: map[string]interface{}{"sort": "id"}},
+				{Name: "by name", Data: map[string]interface{}{"sort": "name"}},
+			},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	dot, opts := newOptions(t)
+
+	res, err := Render(dot, "postgres", opts)
+	require.Nil(t, err)
+	assert.Equal(t, `-- name: select_user
+SELECT
+id, name
+FROM users WHERE id = $1
+
+-- name: select_users
+-- sample: by id
+SELECT * FROM users ORDER BY id
+
+-- name: select_users
+-- sample: by name
+SELECT * FROM users ORDER BY name
+
+-- name: upsert_user
+-- dialect: postgres
+INSERT INTO users (id, name) VALUES ($1, $2)
+ON CONFLICT (id) DO UPDATE SET name = excluded.name
+`, string(res))
+
+	// no variant for the dialect
+	_, err = Render(dot, "mysql", opts)
+	assert.Equal(t, &dotsqlx.QueryError{Name: "upsert_user", Err: dotsqlx.ErrNoVariant}, err)
+
+	_, err = Render(dot, "", opts)
+	assert.Equal(t, &dotsqlx.QueryError{Name: "upsert_user", Err: dotsqlx.ErrNoVariant}, err)
+
+	// invalid sample
+	opts.Samples["select_users"] = []Sample{{Name: "by age", Data: map[string]interface{}{"sort": "age"}}}
+	_, err = Render(dot, "sqlite3", opts)
+	assert.NotNil(t, err)
+}
+
+func TestAssert(t *testing.T) {
+	dot, opts := newOptions(t)
+	Assert(t, dot, opts)
+}
+
+// recorder records test failures.
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprint(args...))
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestAssertFailures(t *testing.T) {
+	dot, opts := newOptions(t)
+	dir, err := ioutil.TempDir("", "dotsqlx")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	opts.Dir = dir
+
+	// missing golden files
+	r := &recorder{TB: t}
+	Assert(r, dot, opts)
+	require.Len(t, r.errs, 2)
+	assert.Contains(t, r.errs[0], "run the tests with -golden.update to create it")
+
+	// outdated golden files
+	for _, d := range opts.Drivers {
+		require.Nil(t, ioutil.WriteFile(filepath.Join(opts.Dir, d+".sql"), []byte("-- name: select_user\nSELECT 1\n"), 0644))
+	}
+
+	r = &recorder{TB: t}
+	Assert(r, dot, opts)
+	require.Len(t, r.errs, 2)
+	assert.Contains(t, r.errs[0], "is out of date")
+
+	// rendering errors
+	opts.Drivers = []string{"mysql"}
+
+	r = &recorder{TB: t}
+	Assert(r, dot, opts)
+	assert.Len(t, r.errs, 1)
+}
+
+func TestAssertUpdate(t *testing.T) {
+	dot, opts := newOptions(t)
+	dir, err := ioutil.TempDir("", "dotsqlx")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	opts.Dir = filepath.Join(dir, "golden")
+
+	*update = true
+	defer func() { *update = false }()
+
+	Assert(t, dot, opts)
+
+	*update = false
+	Assert(t, dot, opts)
+
+	res, err := ioutil.ReadFile(filepath.Join(opts.Dir, "sqlite3.sql"))
+	require.Nil(t, err)
+	assert.Contains(t, string(res), "INSERT OR REPLACE INTO users (id, name) VALUES (?, ?)")
+}
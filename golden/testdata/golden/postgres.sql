@@ -0,0 +1,17 @@
+-- name: select_user
+SELECT
+id, name
+FROM users WHERE id = $1
+
+-- name: select_users
+-- sample: by id
+SELECT * FROM users ORDER BY id
+
+-- name: select_users
+-- sample: by name
+SELECT * FROM users ORDER BY name
+
+-- name: upsert_user
+-- dialect: postgres
+INSERT INTO users (id, name) VALUES ($1, $2)
+ON CONFLICT (id) DO UPDATE SET name = excluded.name
@@ -0,0 +1,16 @@
+-- name: select_user
+SELECT
+id, name
+FROM users WHERE id = ?
+
+-- name: select_users
+-- sample: by id
+SELECT * FROM users ORDER BY id
+
+-- name: select_users
+-- sample: by name
+SELECT * FROM users ORDER BY name
+
+-- name: upsert_user
+-- dialect: sqlite
+INSERT OR REPLACE INTO users (id, name) VALUES (?, ?)
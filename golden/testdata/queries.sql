@@ -0,0 +1,20 @@
+-- name: columns
+-- fragment: true
+id, name
+
+-- name: select_user
+SELECT
+-- include: columns
+FROM users WHERE id = ?
+
+-- name: select_users
+SELECT * FROM users ORDER BY {{ ident .sort }}
+
+-- name: upsert_user
+-- dialect: postgres
+INSERT INTO users (id, name) VALUES (?, ?)
+ON CONFLICT (id) DO UPDATE SET name = excluded.name
+
+-- name: upsert_user
+-- dialect: sqlite
+INSERT OR REPLACE INTO users (id, name) VALUES (?, ?)
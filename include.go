@@ -0,0 +1,146 @@
+package dotsqlx
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrFragment is returned when a query marked with the `-- fragment:`
+// annotation is executed.
+var ErrFragment = errors.New("dotsqlx: fragments cannot be executed")
+
+// includeRe matches the `-- include:` line.
+var includeRe = regexp.MustCompile(`^\s*--\s*include:\s*(\S+)\s*$`)
+
+// IsFragment reports whether the definition is marked with the
+// `-- fragment:` annotation, i.e. it is only meant to be included in other
+// queries.
+func (d Definition) IsFragment() bool {
+	return d.Annotations.Get("fragment") != ""
+}
+
+// ResolveIncludes returns a copy of the definitions in which every
+// `-- include: <name>` line is replaced by the body of the named definition,
+// without its annotations. Definitions of the same dialect are preferred
+// over dialect-neutral ones and, as with Load, a definition in a later file
+// replaces the ones in earlier files. The first unknown include or include cycle is
+// returned as an error along with its source location.
+func ResolveIncludes(dd []Definition) ([]Definition, error) {
+	res, ii := resolveIncludes(dd)
+	if len(ii) > 0 {
+		return nil, fmt.Errorf("dotsqlx: %s", ii[0])
+	}
+
+	return res, nil
+}
+
+// resolveIncludes expands the includes of all definitions and returns all
+// found issues.
+func resolveIncludes(dd []Definition) ([]Definition, []Issue) {
+	const (
+		pending = iota
+		active
+		done
+		failed
+	)
+
+	var (
+		ii    []Issue
+		res   = make([]Definition, len(dd))
+		state = make([]int, len(dd))
+		index = make(map[string]map[string]int)
+		stack []string
+	)
+
+	copy(res, dd)
+
+	for i, d := range dd {
+		if index[d.Name] == nil {
+			index[d.Name] = make(map[string]int)
+		}
+
+		// As with Load, a definition in a later file replaces the earlier
+		// ones, while a definition repeated in the same file doesn't.
+		dialect := d.Annotations.Get("dialect")
+		if j, ok := index[d.Name][dialect]; !ok || dd[j].File != d.File {
+			index[d.Name][dialect] = i
+		}
+	}
+
+	var expand func(i int) bool
+	expand = func(i int) bool {
+		switch state[i] {
+		case done:
+			return true
+		case failed:
+			return false
+		}
+
+		state[i] = active
+		stack = append(stack, dd[i].Name)
+
+		defer func() {
+			stack = stack[:len(stack)-1]
+		}()
+
+		fail := func(format string, args ...interface{}) bool {
+			ii = append(ii, Issue{Definition: dd[i], Message: fmt.Sprintf(format, args...)})
+			state[i] = failed
+			return false
+		}
+
+		var lines []string
+		for _, line := range strings.Split(dd[i].SQL, "\n") {
+			m := includeRe.FindStringSubmatch(line)
+			if m == nil {
+				lines = append(lines, line)
+				continue
+			}
+
+			v := index[m[1]]
+			j, ok := v[dd[i].Annotations.Get("dialect")]
+			if !ok {
+				if j, ok = v[""]; !ok {
+					return fail("unknown include %q", m[1])
+				}
+			}
+
+			if state[j] == active {
+				return fail("include cycle: %s -> %s", strings.Join(stack, " -> "), m[1])
+			}
+
+			if !expand(j) {
+				state[i] = failed
+				return false
+			}
+
+			lines = append(lines, stripAnnotations(res[j].SQL)...)
+		}
+
+		res[i].SQL = strings.Join(lines, "\n")
+		res[i].Annotations = ParseAnnotations(res[i].SQL)
+		state[i] = done
+		return true
+	}
+
+	for i := range dd {
+		expand(i)
+	}
+
+	return res, ii
+}
+
+// stripAnnotations returns the lines of the query that follow its leading
+// annotations.
+func stripAnnotations(query string) []string {
+	lines := strings.Split(query, "\n")
+	for i, line := range lines {
+		if !annotationRe.MatchString(line) || includeRe.MatchString(line) {
+			return lines[i:]
+		}
+	}
+
+	return nil
+}
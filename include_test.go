@@ -0,0 +1,176 @@
+package dotsqlx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveIncludes(t *testing.T) {
+	cc := map[string]struct {
+		Queries string
+		SQL     []string
+		Err     string
+	}{
+		"unknown include": {
+			Queries: `
+-- name: a
+SELECT 1
+-- include: b`,
+			Err: `dotsqlx: q.sql:2: a: unknown include "b"`,
+		},
+		"cycle": {
+			Queries: `
+-- name: a
+-- include: b
+
+-- name: b
+-- include: c
+
+-- name: c
+-- include: a`,
+			Err: "dotsqlx: q.sql:8: c: include cycle: a -> b -> c -> a",
+		},
+		"nested includes": {
+			Queries: `
+-- name: cols
+-- fragment: true
+a, b
+
+-- name: from
+-- fragment: true
+-- include: cols
+FROM t
+
+-- name: select
+-- tags: x
+SELECT
+-- include: from`,
+			SQL: []string{
+				"-- fragment: true\na, b",
+				"-- fragment: true\na, b\nFROM t",
+				"-- tags: x\nSELECT\na, b\nFROM t",
+			},
+		},
+		"dialect include": {
+			Queries: `
+-- name: now
+-- dialect: postgres
+now()
+
+-- name: now
+datetime('now')
+
+-- name: select
+-- dialect: postgres
+SELECT
+-- include: now
+
+-- name: select
+SELECT
+-- include: now`,
+			SQL: []string{
+				"-- dialect: postgres\nnow()",
+				"datetime('now')",
+				"-- dialect: postgres\nSELECT\nnow()",
+				"SELECT\ndatetime('now')",
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			dd, err := ParseDefinitions(strings.NewReader(c.Queries), "q.sql")
+			require.Nil(t, err)
+
+			res, err := ResolveIncludes(dd)
+			if c.Err != "" {
+				assert.EqualError(t, err, c.Err)
+				return
+			}
+
+			require.Nil(t, err)
+			require.Len(t, res, len(c.SQL))
+
+			for i, d := range res {
+				assert.Equal(t, c.SQL[i], d.SQL)
+				assert.Equal(t, ParseAnnotations(c.SQL[i]), d.Annotations)
+			}
+
+			assert.NotEqual(t, dd[len(dd)-1].SQL, res[len(res)-1].SQL)
+		})
+	}
+}
+
+func TestResolveIncludesOverride(t *testing.T) {
+	dd, err := ParseDefinitions(strings.NewReader(`
+-- name: cols
+-- fragment: true
+a
+
+-- name: select
+SELECT
+-- include: cols
+FROM t`), "a.sql")
+	require.Nil(t, err)
+
+	odd, err := ParseDefinitions(strings.NewReader(`
+-- name: cols
+-- fragment: true
+a, b`), "b.sql")
+	require.Nil(t, err)
+
+	res, err := ResolveIncludes(append(dd, odd...))
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT\na, b\nFROM t", res[1].SQL)
+}
+
+func TestLoad(t *testing.T) {
+	_, err := Load("testdata/missing.sql")
+	assert.NotNil(t, err)
+
+	dot, err := Load("testdata/includes.sql", "testdata/overrides.sql")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"select_even", "select_nr"}, dot.Names())
+
+	q, err := dot.Raw("select_nr")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT nr FROM numbers ORDER BY nr DESC", q)
+
+	_, err = dot.Raw("even_only")
+	assert.Equal(t, &QueryError{Name: "even_only", Err: ErrFragment}, err)
+
+	db := newDB(t, 5)
+
+	var rows []struct {
+		Nr     int
+		Double int
+	}
+	require.Nil(t, dot.Select(db, &rows, "select_even"))
+	assert.Len(t, rows, 3)
+	assert.Equal(t, 8, rows[2].Double)
+
+	var n int
+	assert.Equal(t, &QueryError{Name: "number_columns", Err: ErrFragment}, dot.Get(db, &n, "number_columns"))
+}
+
+func TestValidateIncludes(t *testing.T) {
+	dd, err := ParseDefinitions(strings.NewReader(`
+-- name: a
+-- include: b
+
+-- name: c
+-- include: missing`), "q.sql")
+	require.Nil(t, err)
+
+	ii := Validate(dd)
+	require.Len(t, ii, 2)
+	assert.Equal(t, `q.sql:2: a: unknown include "b"`, ii[0].String())
+	assert.Equal(t, `q.sql:5: c: unknown include "missing"`, ii[1].String())
+}
@@ -0,0 +1,311 @@
+package dotsqlx
+
+import (
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// tokenKind is the kind of a lexed SQL fragment.
+type tokenKind int
+
+const (
+	tokenCode tokenKind = iota
+	tokenString
+	tokenQuoted
+	tokenComment
+	tokenTemplate
+)
+
+// token is a fragment of SQL that is either plain code, a string literal
+// (including dollar-quoted bodies), a quoted identifier, a comment or a
+// text/template action.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lex splits the query into tokens. False is returned if a string literal,
+// a quoted identifier, a block comment or a template action is not
+// terminated; the last token then holds the remaining part of the query.
+//
+// Backslashes escape the next character in `E'...'` literals. In other
+// literals doubled quotes are the only escapes, as the SQL standard
+// defines, unless a literal seems to end with a backslash escaped quote,
+// as written for MySQL, e.g. 'it\'s', and the query can be lexed with
+// backslash escapes.
+func lex(query string) ([]token, bool) {
+	tt, ok := lexQuery(query, false)
+	if ok && !endsEscaped(tt) {
+		return tt, true
+	}
+
+	if et, eok := lexQuery(query, true); eok {
+		return et, true
+	}
+
+	return tt, ok
+}
+
+// endsEscaped reports whether any string literal or quoted identifier
+// ends with a backslash followed by the closing quote.
+func endsEscaped(tt []token) bool {
+	for _, t := range tt {
+		if (t.kind == tokenString || t.kind == tokenQuoted) && len(t.text) > 2 && t.text[len(t.text)-2] == '\\' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lexQuery splits the query into tokens like lex. If escapes is true,
+// backslashes escape the next character in all string literals and double
+// quoted identifiers.
+func lexQuery(query string, escapes bool) ([]token, bool) {
+	var (
+		tt    []token
+		start int
+	)
+
+	emit := func(kind tokenKind, end int) {
+		if end > start {
+			tt = append(tt, token{kind: kind, text: query[start:end]})
+		}
+
+		start = end
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == '{' && strings.HasPrefix(query[i:], "{{"):
+			emit(tokenCode, i)
+
+			end := closeAction(query, i+2)
+			if end < 0 {
+				emit(tokenTemplate, len(query))
+				return tt, false
+			}
+
+			emit(tokenTemplate, end)
+			i = end
+		case c == '\'' || c == '"' || c == '`':
+			esc := escapes && c != '`'
+			if c == '\'' && isEscapePrefix(query, i) {
+				esc = true
+				emit(tokenCode, i-1)
+			} else {
+				emit(tokenCode, i)
+			}
+
+			end := closeQuote(query, i+1, c, esc)
+			kind := tokenString
+			if c != '\'' {
+				kind = tokenQuoted
+			}
+
+			if end < 0 {
+				emit(kind, len(query))
+				return tt, false
+			}
+
+			emit(kind, end)
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			emit(tokenCode, i)
+
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i
+			}
+
+			emit(tokenComment, end)
+			i = end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			emit(tokenCode, i)
+
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				emit(tokenComment, len(query))
+				return tt, false
+			}
+
+			end += i + 4
+			emit(tokenComment, end)
+			i = end
+		case c == '$':
+			tag := dollarTag(query[i:])
+			if tag == "" {
+				i++
+				continue
+			}
+
+			emit(tokenCode, i)
+
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				emit(tokenString, len(query))
+				return tt, false
+			}
+
+			end += i + 2*len(tag)
+			emit(tokenString, end)
+			i = end
+		default:
+			i++
+		}
+	}
+
+	emit(tokenCode, len(query))
+
+	return tt, true
+}
+
+// stripComments returns the query without comments. Block comments are
+// replaced with a space, so that the code around them stays separated.
+func stripComments(query string) string {
+	var b strings.Builder
+
+	tt, _ := lex(query)
+	for _, t := range tt {
+		switch {
+		case t.kind != tokenComment:
+			b.WriteString(t.text)
+		case strings.HasPrefix(t.text, "/*"):
+			b.WriteByte(' ')
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// closeQuote returns the index right after the quote closing the literal
+// that starts at i or -1 if it is not closed. Doubled quotes are treated
+// as escaped ones, as are characters following a backslash if escapes is
+// true.
+func closeQuote(query string, i int, q byte, escapes bool) int {
+	for ; i < len(query); i++ {
+		if escapes && query[i] == '\\' {
+			i++
+			continue
+		}
+
+		if query[i] != q {
+			continue
+		}
+
+		if i+1 < len(query) && query[i+1] == q {
+			i++
+			continue
+		}
+
+		return i + 1
+	}
+
+	return -1
+}
+
+// isEscapePrefix reports whether the quote at i starts an `E'...'` literal.
+func isEscapePrefix(query string, i int) bool {
+	if i == 0 || (query[i-1] != 'E' && query[i-1] != 'e') {
+		return false
+	}
+
+	return i == 1 || !isWordByte(query[i-2])
+}
+
+// closeAction returns the index right after the `}}` closing the template
+// action that starts at i or -1 if it is not closed. Delimiters inside the
+// action's string literals are ignored.
+func closeAction(query string, i int) int {
+	for ; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '"' || c == '\'' || c == '`':
+			end := closeQuote(query, i+1, c, c != '`')
+			if end < 0 {
+				return -1
+			}
+
+			i = end - 1
+		case c == '}' && strings.HasPrefix(query[i:], "}}"):
+			return i + 2
+		}
+	}
+
+	return -1
+}
+
+// dollarTag returns the dollar quote tag, e.g. "$$" or "$body$", that s
+// starts with or an empty string if there is none.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || isLetter(c) || (i > 1 && isDigit(c)):
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// BindTypes returns the jmoiron/sqlx bindvar types used by the query:
+// sqlx.QUESTION for `?`, sqlx.DOLLAR for `$1`, sqlx.NAMED for `:name` and
+// sqlx.AT for `@name`. Bindvars inside string literals, quoted identifiers
+// and comments are ignored, as are `::` casts.
+func BindTypes(query string) []int {
+	found := make(map[int]bool)
+
+	tt, _ := lex(query)
+	for _, t := range tt {
+		if t.kind != tokenCode {
+			continue
+		}
+
+		s := t.text
+		for i := 0; i < len(s); i++ {
+			next := byte(0)
+			if i+1 < len(s) {
+				next = s[i+1]
+			}
+
+			switch {
+			case s[i] == '?':
+				found[sqlx.QUESTION] = true
+			case s[i] == '$' && isDigit(next):
+				found[sqlx.DOLLAR] = true
+			case s[i] == ':' && next == ':':
+				i++
+			case s[i] == ':' && (isLetter(next) || next == '_'):
+				found[sqlx.NAMED] = true
+			case s[i] == '@' && (isLetter(next) || next == '_'):
+				found[sqlx.AT] = true
+			}
+		}
+	}
+
+	var res []int
+	for _, bt := range []int{sqlx.QUESTION, sqlx.DOLLAR, sqlx.NAMED, sqlx.AT} {
+		if found[bt] {
+			res = append(res, bt)
+		}
+	}
+
+	return res
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
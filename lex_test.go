@@ -0,0 +1,110 @@
+package dotsqlx
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLex(t *testing.T) {
+	tt, ok := lex("SELECT 'it''s', \"a\"\"b\" -- c\nFROM t /* d */ WHERE b = $x$ '$x$")
+	assert.True(t, ok)
+	assert.Equal(t, []token{
+		{kind: tokenCode, text: "SELECT "},
+		{kind: tokenString, text: "'it''s'"},
+		{kind: tokenCode, text: ", "},
+		{kind: tokenQuoted, text: "\"a\"\"b\""},
+		{kind: tokenCode, text: " "},
+		{kind: tokenComment, text: "-- c"},
+		{kind: tokenCode, text: "\nFROM t "},
+		{kind: tokenComment, text: "/* d */"},
+		{kind: tokenCode, text: " WHERE b = "},
+		{kind: tokenString, text: "$x$ '$x$"},
+	}, tt)
+
+	_, ok = lex("SELECT 'a")
+	assert.False(t, ok)
+
+	_, ok = lex("SELECT `a")
+	assert.False(t, ok)
+
+	_, ok = lex("SELECT /* a")
+	assert.False(t, ok)
+
+	_, ok = lex("SELECT $$ a")
+	assert.False(t, ok)
+
+	tt, ok = lex("SELECT $1, $ 1")
+	assert.True(t, ok)
+	assert.Equal(t, []token{{kind: tokenCode, text: "SELECT $1, $ 1"}}, tt)
+}
+
+func TestLexEscapes(t *testing.T) {
+	// escape string literals
+	tt, ok := lex("SELECT E'a\\'b', e'\\\\', 'c:\\'")
+	assert.True(t, ok)
+	assert.Equal(t, []token{
+		{kind: tokenCode, text: "SELECT "},
+		{kind: tokenString, text: "E'a\\'b'"},
+		{kind: tokenCode, text: ", "},
+		{kind: tokenString, text: "e'\\\\'"},
+		{kind: tokenCode, text: ", "},
+		{kind: tokenString, text: "'c:\\'"},
+	}, tt)
+
+	// backslash escapes in plain literals
+	tt, ok = lex("SELECT 'it\\'s -- a', \"b\\\"c\" FROM t")
+	assert.True(t, ok)
+	assert.Equal(t, []token{
+		{kind: tokenCode, text: "SELECT "},
+		{kind: tokenString, text: "'it\\'s -- a'"},
+		{kind: tokenCode, text: ", "},
+		{kind: tokenQuoted, text: "\"b\\\"c\""},
+		{kind: tokenCode, text: " FROM t"},
+	}, tt)
+
+	_, ok = lex("SELECT E'a\\'")
+	assert.False(t, ok)
+
+	// identifiers ending with e
+	tt, ok = lex("SELECT type'a\\'")
+	assert.True(t, ok)
+	assert.Equal(t, token{kind: tokenString, text: "'a\\'"}, tt[1])
+}
+
+func TestLexTemplates(t *testing.T) {
+	tt, ok := lex("SELECT a FROM t {{ if .b }}WHERE b{{ end }} {{ fragment \"'}}\" }}")
+	assert.True(t, ok)
+	assert.Equal(t, []token{
+		{kind: tokenCode, text: "SELECT a FROM t "},
+		{kind: tokenTemplate, text: "{{ if .b }}"},
+		{kind: tokenCode, text: "WHERE b"},
+		{kind: tokenTemplate, text: "{{ end }}"},
+		{kind: tokenCode, text: " "},
+		{kind: tokenTemplate, text: "{{ fragment \"'}}\" }}"},
+	}, tt)
+
+	_, ok = lex("SELECT {{ if .a }")
+	assert.False(t, ok)
+
+	tt, ok = lex("SELECT '{{' -- {{")
+	assert.True(t, ok)
+	for _, tok := range tt {
+		assert.NotEqual(t, tokenTemplate, tok.kind)
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	assert.Equal(t, "SELECT a,   b FROM t \nWHERE c = ':d' AND e = :e",
+		stripComments("-- tags: x\nSELECT a, /* note: y */ b FROM t -- see: z\nWHERE c = ':d' AND e = :e"))
+}
+
+func TestBindTypes(t *testing.T) {
+	assert.Nil(t, BindTypes("SELECT '?', \":a\" -- @b"))
+	assert.Equal(t, []int{sqlx.QUESTION}, BindTypes("SELECT ? FROM t WHERE a = ?"))
+	assert.Equal(t, []int{sqlx.DOLLAR}, BindTypes("SELECT $1::int"))
+	assert.Equal(t, []int{sqlx.NAMED}, BindTypes("SELECT :a, b::text"))
+	assert.Equal(t, []int{sqlx.AT}, BindTypes("SELECT @p1"))
+	assert.Equal(t, []int{sqlx.QUESTION, sqlx.DOLLAR, sqlx.NAMED, sqlx.AT}, BindTypes("SELECT @a, :b, $1, ?"))
+}
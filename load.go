@@ -0,0 +1,60 @@
+package dotsqlx
+
+// Load reads the named queries of all files, resolves their includes and
+// dialect-specific variants and returns a new DotSqlx instance using them.
+// As with dotsql, a query defined again in the same file is appended to
+// the first definition, while a query defined in a later file replaces it.
+// Fragments are not executable.
+func Load(files ...string) (*DotSqlx, error) {
+	dd, err := LoadDefinitions(files...)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromDefinitions(dd)
+}
+
+// FromDefinitions returns a new DotSqlx instance using the definitions, as
+// described by Load.
+func FromDefinitions(dd []Definition) (*DotSqlx, error) {
+	dd, err := ResolveIncludes(dd)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		queries   = make(map[string]string)
+		files     = make(map[string]string)
+		fragments = make(map[string]bool)
+		rest      []Definition
+	)
+
+	for _, def := range dd {
+		if def.IsFragment() {
+			fragments[def.Name] = true
+			continue
+		}
+
+		rest = append(rest, def)
+
+		if file, ok := files[def.Name]; ok && file == def.File {
+			queries[def.Name] += "\n" + def.SQL
+			continue
+		}
+
+		files[def.Name] = def.File
+		queries[def.Name] = def.SQL
+	}
+
+	for name := range queries {
+		delete(fragments, name)
+	}
+
+	d := WrapSource(MapSource(queries))
+	if d, err = d.WithVariants(rest); err != nil {
+		return nil, err
+	}
+
+	d.fragments = fragments
+	return d, nil
+}
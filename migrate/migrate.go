@@ -0,0 +1,453 @@
+// Package migrate manages database schemas with dotsql files. Every
+// migration is a file named `<version>_<name>.sql`, e.g.
+// `0003_add_users.sql`, holding an `up` query that applies it and an
+// optional `down` query that reverts it:
+//
+//	-- name: up
+//	CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+//
+//	-- name: down
+//	DROP TABLE users;
+//
+// Migration files are loaded with dotsqlx.Load, so they can use dialect
+// variants and includes.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swithek/dotsqlx"
+)
+
+// DefaultTable is the name of the table applied migrations are recorded in
+// when none is set.
+const DefaultTable = "schema_migrations"
+
+var (
+	// ErrLocked is returned when another migrator holds the lock.
+	ErrLocked = errors.New("dotsqlx: migrations are locked")
+
+	// ErrChecksum is returned when an applied migration has changed since
+	// it was applied.
+	ErrChecksum = errors.New("dotsqlx: migration checksum mismatch")
+
+	// ErrUnknown is returned when an applied migration has no file.
+	ErrUnknown = errors.New("dotsqlx: migration file not found")
+
+	// ErrNoDown is returned when an applied migration that has to be
+	// rolled back has no `down` query.
+	ErrNoDown = errors.New("dotsqlx: migration cannot be rolled back")
+)
+
+// fileRe matches the base name of a migration file.
+var fileRe = regexp.MustCompile(`^(\d+)_(\w+)\.sql$`)
+
+// MigrationError wraps an error of a single migration.
+type MigrationError struct {
+	Version int64
+	Name    string
+	Err     error
+}
+
+// Error returns the version, the name and the error of the migration.
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("%d_%s: %v", e.Version, e.Name, e.Err)
+}
+
+// Unwrap returns the wrapped error.
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// Migration is a loaded migration file.
+type Migration struct {
+	Version int64
+	Name    string
+	File    string
+
+	// Queries holds the `up` and `down` queries.
+	Queries *dotsqlx.DotSqlx
+}
+
+// HasDown returns true if the migration can be rolled back.
+func (m Migration) HasDown() bool {
+	if m.Queries == nil {
+		return false
+	}
+
+	return hasQuery(m.Queries, "down")
+}
+
+// hasQuery returns true if dotsql named query, or any of its variants, is
+// loaded.
+func hasQuery(d *dotsqlx.DotSqlx, name string) bool {
+	for _, n := range d.Names() {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Record is an applied migration, as recorded in the migrations table.
+type Record struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Options holds the options of a Migrator.
+type Options struct {
+	// Table is the name of the table applied migrations are recorded in.
+	// The lock is held in a table of the same name suffixed with `_lock`.
+	// Defaults to DefaultTable.
+	Table string
+}
+
+// Migrator applies and rolls back migrations. Every migration is applied
+// or rolled back in its own transaction, together with its record, so a
+// failing migration leaves no trace; note that some databases, e.g. MySQL,
+// commit schema changes implicitly.
+//
+// Runs are serialised with a lock row, so migrators of several instances
+// can be started at once. The lock is released when a run ends; if a
+// process dies while holding it, it has to be released with Unlock.
+type Migrator struct {
+	db         *sqlx.DB
+	opts       Options
+	migrations []Migration
+}
+
+// New loads the migration files and creates a new Migrator instance.
+func New(db *sqlx.DB, opts Options, files ...string) (*Migrator, error) {
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+
+	m := &Migrator{db: db, opts: opts}
+	versions := make(map[int64]string)
+
+	for _, file := range files {
+		mig, err := load(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if prev, ok := versions[mig.Version]; ok {
+			return nil, fmt.Errorf("dotsqlx: %s: version %d already used by %s", file, mig.Version, prev)
+		}
+
+		versions[mig.Version] = file
+		m.migrations = append(m.migrations, mig)
+	}
+
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+
+	return m, nil
+}
+
+// load reads a migration file.
+func load(file string) (Migration, error) {
+	match := fileRe.FindStringSubmatch(filepath.Base(file))
+	if match == nil {
+		return Migration{}, fmt.Errorf("dotsqlx: %s: invalid migration file name", file)
+	}
+
+	version, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return Migration{}, fmt.Errorf("dotsqlx: %s: %v", file, err)
+	}
+
+	dot, err := dotsqlx.Load(file)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	if !hasQuery(dot, "up") {
+		return Migration{}, fmt.Errorf("dotsqlx: %s: 'up' could not be found", file)
+	}
+
+	return Migration{Version: version, Name: match[2], File: file, Queries: dot}, nil
+}
+
+// Migrations returns the loaded migrations, sorted by version.
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+// Applied returns the applied migrations, sorted by version.
+func (m *Migrator) Applied(ctx context.Context) ([]Record, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.applied(ctx)
+}
+
+// Pending returns the migrations that are not applied yet, sorted by
+// version.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	rr, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.pending(rr), nil
+}
+
+// Verify checks that every applied migration still has a file and that
+// its `up` query has not changed since it was applied.
+func (m *Migrator) Verify(ctx context.Context) error {
+	rr, err := m.Applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.verify(rr)
+}
+
+// Up verifies the applied migrations and applies all pending ones, in
+// order of their versions. Pending migrations older than the latest
+// applied one are applied as well. The number of applied migrations is
+// returned.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	var n int
+
+	err := m.run(ctx, func(rr []Record) error {
+		for _, mig := range m.pending(rr) {
+			if err := m.apply(ctx, mig); err != nil {
+				return err
+			}
+
+			n++
+		}
+
+		return nil
+	})
+
+	return n, err
+}
+
+// Down verifies the applied migrations and rolls back all migrations newer
+// than the version, newest first. Version 0 rolls back all migrations. The
+// number of rolled back migrations is returned.
+func (m *Migrator) Down(ctx context.Context, version int64) (int, error) {
+	var n int
+
+	err := m.run(ctx, func(rr []Record) error {
+		for i := len(rr) - 1; i >= 0 && rr[i].Version > version; i-- {
+			if err := m.revert(ctx, m.find(rr[i].Version)); err != nil {
+				return err
+			}
+
+			n++
+		}
+
+		return nil
+	})
+
+	return n, err
+}
+
+// Unlock releases the lock, e.g. after a process died while holding it.
+func (m *Migrator) Unlock(ctx context.Context) error {
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+
+	_, err := m.db.ExecContext(ctx, "DELETE FROM "+m.opts.Table+"_lock")
+	return err
+}
+
+// init creates the migrations and the lock tables if they do not exist.
+func (m *Migrator) init(ctx context.Context) error {
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS " + m.opts.Table + ` (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`,
+		"CREATE TABLE IF NOT EXISTS " + m.opts.Table + `_lock (
+			id INTEGER PRIMARY KEY,
+			locked_at TIMESTAMP NOT NULL
+		)`,
+	} {
+		if _, err := m.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run calls fn with the verified applied migrations while holding the
+// lock.
+func (m *Migrator) run(ctx context.Context, fn func([]Record) error) error {
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+
+	// the lock must be released even if the context is cancelled
+	defer m.db.ExecContext(context.Background(), "DELETE FROM "+m.opts.Table+"_lock")
+
+	rr, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.verify(rr); err != nil {
+		return err
+	}
+
+	return fn(rr)
+}
+
+// lock inserts the lock row. ErrLocked is returned if it already exists.
+func (m *Migrator) lock(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, m.db.Rebind("INSERT INTO "+m.opts.Table+"_lock (id, locked_at) VALUES (1, ?)"), time.Now().UTC())
+	if err == nil {
+		return nil
+	}
+
+	var n int
+	if cerr := m.db.GetContext(ctx, &n, "SELECT COUNT(*) FROM "+m.opts.Table+"_lock"); cerr == nil && n > 0 {
+		return ErrLocked
+	}
+
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) ([]Record, error) {
+	var rr []Record
+	if err := m.db.SelectContext(ctx, &rr, "SELECT version, name, checksum, applied_at FROM "+m.opts.Table+" ORDER BY version"); err != nil {
+		return nil, err
+	}
+
+	return rr, nil
+}
+
+func (m *Migrator) pending(rr []Record) []Migration {
+	applied := make(map[int64]bool, len(rr))
+	for _, r := range rr {
+		applied[r.Version] = true
+	}
+
+	var mm []Migration
+	for _, mig := range m.migrations {
+		if !applied[mig.Version] {
+			mm = append(mm, mig)
+		}
+	}
+
+	return mm
+}
+
+func (m *Migrator) verify(rr []Record) error {
+	for _, r := range rr {
+		mig := m.find(r.Version)
+		if mig.Queries == nil {
+			return &MigrationError{Version: r.Version, Name: r.Name, Err: ErrUnknown}
+		}
+
+		sum, err := m.checksum(mig)
+		if err != nil {
+			return &MigrationError{Version: mig.Version, Name: mig.Name, Err: err}
+		}
+
+		if sum != r.Checksum {
+			return &MigrationError{Version: mig.Version, Name: mig.Name, Err: ErrChecksum}
+		}
+	}
+
+	return nil
+}
+
+// find returns the migration of the version, or a zero Migration if it is
+// not loaded.
+func (m *Migrator) find(version int64) Migration {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+
+	return Migration{}
+}
+
+// checksum returns the fingerprint of the `up` query the migration uses
+// with the driver of the database.
+func (m *Migrator) checksum(mig Migration) (string, error) {
+	query, err := mig.Queries.RawFor(m.db.DriverName(), "up")
+	if err != nil {
+		return "", err
+	}
+
+	return dotsqlx.Fingerprint(query), nil
+}
+
+// apply applies the migration and records it in one transaction.
+func (m *Migrator) apply(ctx context.Context, mig Migration) error {
+	sum, err := m.checksum(mig)
+	if err != nil {
+		return &MigrationError{Version: mig.Version, Name: mig.Name, Err: err}
+	}
+
+	return m.tx(ctx, mig, func(tx *sqlx.Tx) error {
+		if _, err := mig.Queries.ExecContext(ctx, tx, "up"); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, tx.Rebind("INSERT INTO "+m.opts.Table+" (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+			mig.Version, mig.Name, sum, time.Now().UTC())
+		return err
+	})
+}
+
+// revert rolls back the migration and deletes its record in one
+// transaction.
+func (m *Migrator) revert(ctx context.Context, mig Migration) error {
+	if !mig.HasDown() {
+		return &MigrationError{Version: mig.Version, Name: mig.Name, Err: ErrNoDown}
+	}
+
+	return m.tx(ctx, mig, func(tx *sqlx.Tx) error {
+		if _, err := mig.Queries.ExecContext(ctx, tx, "down"); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM "+m.opts.Table+" WHERE version = ?"), mig.Version)
+		return err
+	})
+}
+
+// tx calls fn in a transaction that is committed if fn succeeds.
+func (m *Migrator) tx(ctx context.Context, mig Migration, fn func(*sqlx.Tx) error) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return &MigrationError{Version: mig.Version, Name: mig.Name, Err: err}
+	}
+
+	return tx.Commit()
+}
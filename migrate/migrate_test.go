@@ -0,0 +1,214 @@
+package migrate
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	require.Nil(t, err)
+	db.SetMaxOpenConns(1)
+
+	return db
+}
+
+// writeFile writes a migration file to the directory and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	file := filepath.Join(dir, name)
+	require.Nil(t, ioutil.WriteFile(file, []byte(content), 0644))
+
+	return file
+}
+
+func testFiles(t *testing.T) []string {
+	ff, err := filepath.Glob("testdata/*.sql")
+	require.Nil(t, err)
+
+	return ff
+}
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotsqlx")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cc := map[string]struct {
+		Files []string
+		Err   string
+	}{
+		"Invalid file name": {
+			Files: []string{writeFile(t, dir, "add_users.sql", "-- name: up\nSELECT 1")},
+			Err:   "invalid migration file name",
+		},
+		"Missing file": {
+			Files: []string{filepath.Join(dir, "0001_missing.sql")},
+			Err:   "no such file",
+		},
+		"Missing up query": {
+			Files: []string{writeFile(t, dir, "0001_no_up.sql", "-- name: down\nSELECT 1")},
+			Err:   "'up' could not be found",
+		},
+		"Duplicate version": {
+			Files: []string{
+				writeFile(t, dir, "0001_first.sql", "-- name: up\nSELECT 1"),
+				writeFile(t, dir, "01_second.sql", "-- name: up\nSELECT 2"),
+			},
+			Err: "version 1 already used",
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			_, err := New(nil, Options{}, c.Files...)
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), c.Err)
+		})
+	}
+
+	m, err := New(nil, Options{}, "testdata/0003_seed_users.sql", "testdata/0001_create_users.sql")
+	require.Nil(t, err)
+
+	mm := m.Migrations()
+	require.Len(t, mm, 2)
+	assert.Equal(t, int64(1), mm[0].Version)
+	assert.Equal(t, "create_users", mm[0].Name)
+	assert.True(t, mm[0].HasDown())
+	assert.Equal(t, int64(3), mm[1].Version)
+	assert.Equal(t, DefaultTable, m.opts.Table)
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer db.Close()
+
+	m, err := New(db, Options{}, testFiles(t)...)
+	require.Nil(t, err)
+
+	pp, err := m.Pending(ctx)
+	require.Nil(t, err)
+	assert.Len(t, pp, 3)
+
+	n, err := m.Up(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	rr, err := m.Applied(ctx)
+	require.Nil(t, err)
+	require.Len(t, rr, 3)
+	assert.Equal(t, int64(2), rr[1].Version)
+	assert.Equal(t, "create_posts", rr[1].Name)
+	assert.Len(t, rr[1].Checksum, 64)
+	assert.False(t, rr[1].AppliedAt.IsZero())
+
+	// the sqlite variant was applied
+	var name string
+	require.Nil(t, db.Get(&name, "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'posts'"))
+	assert.Equal(t, "posts_user_id", name)
+
+	// nothing pending
+	n, err = m.Up(ctx)
+	require.Nil(t, err)
+	assert.Zero(t, n)
+	assert.Nil(t, m.Verify(ctx))
+
+	// roll back to a version
+	n, err = m.Down(ctx, 1)
+	require.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	rr, err = m.Applied(ctx)
+	require.Nil(t, err)
+	require.Len(t, rr, 1)
+	assert.Equal(t, int64(1), rr[0].Version)
+
+	// roll back all
+	n, err = m.Down(ctx, 0)
+	require.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	var count int
+	require.Nil(t, db.Get(&count, "SELECT COUNT(*) FROM sqlite_master WHERE name IN ('users', 'posts')"))
+	assert.Zero(t, count)
+
+	// the lock is released after every run
+	require.Nil(t, db.Get(&count, "SELECT COUNT(*) FROM schema_migrations_lock"))
+	assert.Zero(t, count)
+}
+
+func TestMigratorFailures(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "dotsqlx")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	db := newDB(t)
+	defer db.Close()
+
+	users := writeFile(t, dir, "0001_create_users.sql", "-- name: up\nCREATE TABLE users (id INTEGER PRIMARY KEY)")
+	invalid := writeFile(t, dir, "0002_invalid.sql", "-- name: up\nCREATE TABLE posts (id INTEGER PRIMARY KEY);\nINSERT INTO missing VALUES (1)")
+
+	m, err := New(db, Options{Table: "migrations"}, users, invalid)
+	require.Nil(t, err)
+
+	// a failing migration is rolled back
+	n, err := m.Up(ctx)
+	assert.Equal(t, 1, n)
+
+	merr, ok := err.(*MigrationError)
+	require.True(t, ok)
+	assert.Equal(t, int64(2), merr.Version)
+	assert.Contains(t, err.Error(), "2_invalid: no such table: missing")
+
+	var count int
+	require.Nil(t, db.Get(&count, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'posts'"))
+	assert.Zero(t, count)
+
+	rr, err := m.Applied(ctx)
+	require.Nil(t, err)
+	assert.Len(t, rr, 1)
+
+	// locked
+	db.MustExec("INSERT INTO migrations_lock (id, locked_at) VALUES (1, CURRENT_TIMESTAMP)")
+
+	_, err = m.Up(ctx)
+	assert.Equal(t, ErrLocked, err)
+
+	require.Nil(t, m.Unlock(ctx))
+
+	// no down query
+	_, err = m.Down(ctx, 0)
+	assert.Equal(t, &MigrationError{Version: 1, Name: "create_users", Err: ErrNoDown}, err)
+
+	// changed migration
+	writeFile(t, dir, "0001_create_users.sql", "-- name: up\nCREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+
+	m, err = New(db, Options{Table: "migrations"}, users)
+	require.Nil(t, err)
+	assert.Equal(t, &MigrationError{Version: 1, Name: "create_users", Err: ErrChecksum}, m.Verify(ctx))
+
+	_, err = m.Up(ctx)
+	assert.Equal(t, &MigrationError{Version: 1, Name: "create_users", Err: ErrChecksum}, err)
+
+	// layout changes do not change the checksum
+	writeFile(t, dir, "0001_create_users.sql", "-- name: up\n-- the users\ncreate table  users (id\tINTEGER PRIMARY KEY)")
+
+	m, err = New(db, Options{Table: "migrations"}, users)
+	require.Nil(t, err)
+	assert.Nil(t, m.Verify(ctx))
+
+	// unknown migration
+	m, err = New(db, Options{Table: "migrations"})
+	require.Nil(t, err)
+	assert.Equal(t, &MigrationError{Version: 1, Name: "create_users", Err: ErrUnknown}, m.Verify(ctx))
+}
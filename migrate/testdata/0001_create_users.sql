@@ -0,0 +1,8 @@
+-- name: up
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL
+);
+
+-- name: down
+DROP TABLE users;
@@ -0,0 +1,11 @@
+-- name: up
+-- dialect: postgres
+CREATE TABLE posts (id SERIAL PRIMARY KEY, user_id INTEGER REFERENCES users (id), body TEXT);
+
+-- name: up
+-- dialect: sqlite
+CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER REFERENCES users (id), body TEXT);
+CREATE INDEX posts_user_id ON posts (user_id);
+
+-- name: down
+DROP TABLE posts;
@@ -0,0 +1,5 @@
+-- name: up
+INSERT INTO users (id, name) VALUES (1, 'admin');
+
+-- name: down
+DELETE FROM users WHERE id = 1;
@@ -0,0 +1,200 @@
+package dotsqlx
+
+import (
+	"strings"
+)
+
+// KeywordCase is the letter case SQL keywords are converted to.
+type KeywordCase int
+
+// Keyword cases.
+const (
+	KeepCase KeywordCase = iota
+	UpperCase
+	LowerCase
+)
+
+// keywords holds the SQL keywords whose case is changed by Normalize and
+// Format.
+var keywords = toSet(strings.Fields(`
+	ALL ALTER AND ANY AS ASC BETWEEN BY CASE CAST CONFLICT CREATE CROSS
+	DEFAULT DELETE DESC DISTINCT DO DROP ELSE END ESCAPE EXCEPT EXISTS FALSE
+	FETCH FOR FROM FULL GROUP HAVING ILIKE IN INDEX INNER INSERT INTERSECT
+	INTO IS JOIN LATERAL LEFT LIKE LIMIT NATURAL NOT NOTHING NULL NULLS
+	OFFSET ON OR ORDER OUTER OVER PARTITION PRIMARY RECURSIVE RETURNING RIGHT
+	SELECT SET TABLE THEN TRUE UNION UPDATE USING VALUES WHEN WHERE WINDOW
+	WITH
+`))
+
+func toSet(ss []string) map[string]bool {
+	m := make(map[string]bool, len(ss))
+	for _, s := range ss {
+		m[s] = true
+	}
+
+	return m
+}
+
+// NormalizeOptions holds the options of Normalize and Format.
+type NormalizeOptions struct {
+	// KeywordCase is the case SQL keywords are converted to.
+	KeywordCase KeywordCase
+}
+
+// Normalize returns the query with comments removed and whitespace
+// collapsed into single spaces, so that queries differing only in layout
+// are equal. The leading `-- key: value` annotations are kept, one per
+// line. String literals, quoted identifiers, dollar-quoted bodies and
+// text/template actions are left intact.
+func Normalize(query string, opts NormalizeOptions) string {
+	lines := strings.Split(query, "\n")
+
+	var b strings.Builder
+	for len(lines) > 0 && annotationRe.MatchString(lines[0]) {
+		b.WriteString(strings.TrimSpace(lines[0]) + "\n")
+		lines = lines[1:]
+	}
+
+	// a space is written between two pieces of code only if they were
+	// separated by whitespace or a comment
+	var space, written bool
+	emit := func(s string) {
+		if space && written {
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(s)
+		space, written = false, true
+	}
+
+	tt, _ := lex(strings.Join(lines, "\n"))
+	for _, t := range tt {
+		switch t.kind {
+		case tokenComment:
+			space = true
+		case tokenString, tokenQuoted, tokenTemplate:
+			emit(t.text)
+		default:
+			text := changeCase(t.text, opts.KeywordCase)
+			for i := 0; i < len(text); {
+				if isSpace(rune(text[i])) {
+					space = true
+					i++
+					continue
+				}
+
+				j := i
+				for j < len(text) && !isSpace(rune(text[j])) {
+					j++
+				}
+
+				emit(text[i:j])
+				i = j
+			}
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Format returns the query with its line structure and comments kept, but
+// with lines trimmed, empty lines removed, runs of spaces and tabs
+// collapsed and keywords converted to the requested case. String literals,
+// quoted identifiers, dollar-quoted bodies and text/template actions are
+// left intact, apart from the trimming of their lines, which dotsql applies
+// as well.
+func Format(query string, opts NormalizeOptions) string {
+	var b strings.Builder
+
+	tt, _ := lex(query)
+	for _, t := range tt {
+		if t.kind != tokenCode {
+			b.WriteString(t.text)
+			continue
+		}
+
+		text := changeCase(t.text, opts.KeywordCase)
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+			if c == ' ' || c == '\t' {
+				for i+1 < len(text) && (text[i+1] == ' ' || text[i+1] == '\t') {
+					i++
+				}
+
+				c = ' '
+			}
+
+			b.WriteByte(c)
+		}
+	}
+
+	var lines []string
+	for _, l := range strings.Split(b.String(), "\n") {
+		if l = strings.Trim(l, " \t\r"); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Normalize returns a copy of DotSqlx with all queries normalised once. As
+// with RebindAll, the copy holds a snapshot of the queries.
+func (d DotSqlx) Normalize(opts NormalizeOptions) *DotSqlx {
+	n, _ := d.mapQueries(func(query string) (string, error) {
+		return Normalize(query, opts), nil
+	})
+
+	return n
+}
+
+// changeCase converts the keywords of the code to the requested case.
+// Words that are part of bindvars or qualified names are not changed.
+func changeCase(code string, kc KeywordCase) string {
+	if kc == KeepCase {
+		return code
+	}
+
+	b := []byte(code)
+	for i := 0; i < len(b); {
+		if !isWordByte(b[i]) {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(b) && isWordByte(b[j]) {
+			j++
+		}
+
+		prev, next := byte(0), byte(0)
+		if i > 0 {
+			prev = b[i-1]
+		}
+
+		if j < len(b) {
+			next = b[j]
+		}
+
+		word := strings.ToUpper(string(b[i:j]))
+		if keywords[word] && !strings.ContainsRune(":@$.", rune(prev)) && next != '.' {
+			if kc == LowerCase {
+				word = strings.ToLower(word)
+			}
+
+			copy(b[i:j], word)
+		}
+
+		i = j
+	}
+
+	return string(b)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || isLetter(c) || isDigit(c)
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
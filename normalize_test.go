@@ -0,0 +1,83 @@
+package dotsqlx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	cc := map[string]struct {
+		Query  string
+		Opts   NormalizeOptions
+		Result string
+	}{
+		"whitespace and comments": {
+			Query:  "-- tags: a\nSELECT  a,\n\tb -- columns\nFROM t /* table */ WHERE a = 'x  -- y'",
+			Result: "-- tags: a\nSELECT a, b FROM t WHERE a = 'x  -- y'",
+		},
+		"adjacent tokens": {
+			Query:  "SELECT 'a'::text,/* x */b",
+			Result: "SELECT 'a'::text, b",
+		},
+		"upper case": {
+			Query:  "select t.order, :from, \"select\"\nfrom t where\n$$ select  1 $$",
+			Opts:   NormalizeOptions{KeywordCase: UpperCase},
+			Result: "SELECT t.order, :from, \"select\" FROM t WHERE $$ select  1 $$",
+		},
+		"lower case": {
+			Query:  "SELECT Nr FROM Numbers Order By nr",
+			Opts:   NormalizeOptions{KeywordCase: LowerCase},
+			Result: "select Nr from Numbers order by nr",
+		},
+		"escaped quotes": {
+			Query:  "select E'it\\'s -- from', 'a\\'b  from'\nfrom t",
+			Opts:   NormalizeOptions{KeywordCase: UpperCase},
+			Result: "SELECT E'it\\'s -- from', 'a\\'b  from' FROM t",
+		},
+		"template actions": {
+			Query:  "select nr from t\n{{ if .desc }}order  by nr desc{{ end }}",
+			Opts:   NormalizeOptions{KeywordCase: UpperCase},
+			Result: "SELECT nr FROM t {{ if .desc }}ORDER BY nr DESC{{ end }}",
+		},
+		"only annotations": {
+			Query:  "-- fragment: true",
+			Result: "-- fragment: true",
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, c.Result, Normalize(c.Query, c.Opts))
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t,
+		"-- tags: a\nSELECT a, b -- columns\nFROM t\nWHERE a = 'x   y'\nAND b = $$\nselect  1\n$$",
+		Format("-- tags: a\n  select  a,\tb -- columns\n\nfrom   t\nwhere a = 'x   y'  \nand b = $$\n  select  1\n$$", NormalizeOptions{KeywordCase: UpperCase}),
+	)
+}
+
+func TestFormatTemplate(t *testing.T) {
+	assert.Equal(t,
+		"SELECT nr FROM t\n{{ if .desc }}ORDER BY nr DESC{{ end }}\n{{ fragment \"x }}\" }}",
+		Format("select nr from t\n{{ if .desc }}order by nr desc{{ end }}\n{{ fragment \"x }}\" }}", NormalizeOptions{KeywordCase: UpperCase}),
+	)
+}
+
+func TestDotSqlxNormalize(t *testing.T) {
+	dot := newVariantsDot(t, dialectQueries).Normalize(NormalizeOptions{KeywordCase: LowerCase})
+
+	q, err := dot.Raw("select_nr")
+	assert.Nil(t, err)
+	assert.Equal(t, "select nr from numbers", q)
+
+	q, err = dot.RawFor("postgres", "select_now")
+	assert.Nil(t, err)
+	assert.Equal(t, "-- dialect: postgres\nselect cast(now() as TEXT)", q)
+}
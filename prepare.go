@@ -0,0 +1,145 @@
+package dotsqlx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// QueryError is an error of a single dotsql named query.
+type QueryError struct {
+	Name string
+	Err  error
+}
+
+// Error returns the query name followed by its error.
+func (e *QueryError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// PrepareError is returned by PrepareAll and holds the errors of all named
+// queries that could not be prepared, sorted by name.
+type PrepareError struct {
+	Errs []*QueryError
+}
+
+// Error lists all failing queries along with their errors.
+func (e *PrepareError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dotsqlx: %d queries could not be prepared", len(e.Errs))
+
+	for _, qe := range e.Errs {
+		b.WriteString("\n\t")
+		b.WriteString(qe.Error())
+	}
+
+	return b.String()
+}
+
+// Filter reports whether the dotsql named query should be processed.
+type Filter func(name, query string) bool
+
+// WithPrefix returns a Filter matching query names that start with the
+// prefix, e.g. a namespace like "users.".
+func WithPrefix(prefix string) Filter {
+	return func(name, _ string) bool {
+		return strings.HasPrefix(name, prefix)
+	}
+}
+
+// WithTag returns a Filter matching queries that have the tag listed in
+// their `-- tags:` annotation.
+func WithTag(tag string) Filter {
+	return func(_, query string) bool {
+		for _, t := range ParseAnnotations(query).Tags() {
+			if t == tag {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// PrepareAllOptions holds the options of PrepareAll.
+type PrepareAllOptions struct {
+	// Filter selects the queries that should be prepared. All queries are
+	// prepared if it is nil.
+	Filter Filter
+
+	// Cache receives the prepared statements, if set. It should be created
+	// for the same DotSqlx instance. Otherwise, the statements are closed
+	// right after they are prepared.
+	Cache *StmtCache
+}
+
+// PrepareAll prepares every loaded dotsql named query on db, allowing
+// invalid queries to be detected at startup. If any of the queries cannot
+// be prepared, a *PrepareError listing all of them is returned.
+//
+// The filter is applied before queries are checked, so excluded queries
+// are never reported. It receives an empty query if the query has no
+// variant for the driver's dialect. Templated queries, i.e. queries with
+// `{{` actions, are skipped since they can only be prepared once rendered.
+func (d DotSqlx) PrepareAll(ctx context.Context, db *sqlx.DB, opts PrepareAllOptions) error {
+	var perr PrepareError
+	for _, name := range d.Names() {
+		query, err := d.resolve(db.DriverName(), name)
+		if opts.Filter != nil && !opts.Filter(name, query) {
+			continue
+		}
+
+		if err != nil {
+			qe, ok := err.(*QueryError)
+			if !ok {
+				qe = &QueryError{Name: name, Err: err}
+			}
+
+			perr.Errs = append(perr.Errs, qe)
+			continue
+		}
+
+		if isTemplate(query) {
+			continue
+		}
+
+		stmt, err := db.PreparexContext(ctx, query)
+		if err != nil {
+			perr.Errs = append(perr.Errs, &QueryError{Name: name, Err: err})
+			continue
+		}
+
+		if opts.Cache != nil {
+			opts.Cache.store(stmtKey{db: db, name: name}, stmt, 0)
+			continue
+		}
+
+		stmt.Close()
+	}
+
+	if len(perr.Errs) > 0 {
+		return &perr
+	}
+
+	return nil
+}
+
+// isTemplate reports whether the query holds text/template actions outside
+// of string literals, quoted identifiers and comments.
+func isTemplate(query string) bool {
+	tt, _ := lex(query)
+	for _, t := range tt {
+		if t.kind == tokenTemplate {
+			return true
+		}
+	}
+
+	return false
+}
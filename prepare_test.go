@@ -0,0 +1,83 @@
+package dotsqlx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prepareQueries = `
+-- name: users.select
+-- tags: admin
+SELECT nr FROM numbers
+
+-- name: users.invalid
+SELECT nr FROM missing
+
+-- name: events.invalid
+-- tags: admin, reports
+SELECT nr FROM events
+
+-- name: users.sorted
+SELECT nr FROM numbers ORDER BY {{ ident .sort }}
+
+-- name: users.variant
+-- dialect: postgres
+SELECT nr FROM numbers`
+
+func TestPrepareError(t *testing.T) {
+	err := &PrepareError{Errs: []*QueryError{
+		{Name: "a", Err: assert.AnError},
+		{Name: "b", Err: assert.AnError},
+	}}
+
+	assert.Equal(t, "dotsqlx: 2 queries could not be prepared\n\ta: "+assert.AnError.Error()+"\n\tb: "+assert.AnError.Error(), err.Error())
+}
+
+func TestPrepareAll(t *testing.T) {
+	tr := NewTracker()
+	dot := newVariantsDot(t, prepareQueries).WithTracker(tr)
+	db := newDB(t, 0)
+	defer db.Close()
+
+	// all queries
+	err := dot.PrepareAll(context.Background(), db, PrepareAllOptions{})
+	require.IsType(t, &PrepareError{}, err)
+	perr := err.(*PrepareError)
+	require.Len(t, perr.Errs, 3)
+	assert.Equal(t, "events.invalid", perr.Errs[0].Name)
+	assert.NotNil(t, perr.Errs[0].Err)
+	assert.Equal(t, "users.invalid", perr.Errs[1].Name)
+	assert.NotNil(t, perr.Errs[1].Err)
+	assert.Equal(t, "users.variant", perr.Errs[2].Name)
+	assert.Equal(t, ErrNoVariant, perr.Errs[2].Err)
+	assert.Empty(t, tr.Executed())
+
+	// filtered by prefix
+	err = dot.PrepareAll(context.Background(), db, PrepareAllOptions{Filter: WithPrefix("events.")})
+	require.IsType(t, &PrepareError{}, err)
+	assert.Len(t, err.(*PrepareError).Errs, 1)
+
+	// excluded queries without a variant are not reported
+	err = dot.PrepareAll(context.Background(), db, PrepareAllOptions{Filter: WithPrefix("users.s")})
+	assert.Nil(t, err)
+
+	// filtered by tag
+	err = dot.PrepareAll(context.Background(), db, PrepareAllOptions{Filter: WithTag("reports")})
+	require.IsType(t, &PrepareError{}, err)
+	assert.Len(t, err.(*PrepareError).Errs, 1)
+
+	// successful call with cache
+	c := NewStmtCache(dot, 0)
+	defer c.Close()
+
+	err = dot.PrepareAll(context.Background(), db, PrepareAllOptions{Filter: WithPrefix("users.s"), Cache: c})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, c.Len())
+
+	var nrs []int
+	assert.Nil(t, c.SelectContext(context.Background(), db, &nrs, "users.select"))
+	assert.Equal(t, 1, c.Len())
+}
@@ -0,0 +1,249 @@
+package dotsqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/qustavo/dotsql"
+)
+
+// Query is a dotsql named query resolved once, e.g. in a repository
+// constructor, so that missing queries are detected early. Its methods
+// execute the resolved SQL without looking it up again.
+type Query struct {
+	Name        string
+	SQL         string
+	Annotations Annotations
+
+	tracker *Tracker
+}
+
+// Lookup resolves dotsql named query, or its dialect-neutral variant. The
+// lookup itself is not recorded by the tracker, if one is set; every call
+// of a Query method is.
+func (d DotSqlx) Lookup(name string) (Query, error) {
+	return d.LookupFor("", name)
+}
+
+// LookupFor is like Lookup but resolves the variant of dotsql named query
+// for the dialect of the driver, falling back to the dialect-neutral one.
+func (d DotSqlx) LookupFor(driverName, name string) (Query, error) {
+	query, err := d.resolve(driverName, name)
+	if err != nil {
+		return Query{}, err
+	}
+
+	return Query{
+		Name:        name,
+		SQL:         query,
+		Annotations: d.annotations(name, query),
+		tracker:     d.tracker,
+	}, nil
+}
+
+// MustLookup is like Lookup but panics if the query cannot be resolved.
+func (d DotSqlx) MustLookup(name string) Query {
+	q, err := d.Lookup(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return q
+}
+
+// Preparex is a wrapper for jmoiron/sqlx's Preparex().
+func (q Query) Preparex(dbx Preparerx) (*sqlx.Stmt, error) {
+	q.track()
+	return dbx.Preparex(q.SQL)
+}
+
+// PreparexContext is a wrapper for jmoiron/sqlx's PreparexContext().
+func (q Query) PreparexContext(ctx context.Context, dbx PreparerxContext) (*sqlx.Stmt, error) {
+	q.track()
+	return dbx.PreparexContext(ctx, q.SQL)
+}
+
+// Get is a wrapper for jmoiron/sqlx's Get().
+func (q Query) Get(dbx Getter, dest interface{}, args ...interface{}) error {
+	q.track()
+	return dbx.Get(dest, q.SQL, args...)
+}
+
+// GetContext is a wrapper for jmoiron/sqlx's GetContext().
+func (q Query) GetContext(ctx context.Context, dbx GetterContext, dest interface{}, args ...interface{}) error {
+	q.track()
+	return dbx.GetContext(ctx, dest, q.SQL, args...)
+}
+
+// Select is a wrapper for jmoiron/sqlx's Select().
+func (q Query) Select(dbx Selecter, dest interface{}, args ...interface{}) error {
+	q.track()
+	return dbx.Select(dest, q.SQL, args...)
+}
+
+// SelectContext is a wrapper for jmoiron/sqlx's SelectContext().
+func (q Query) SelectContext(ctx context.Context, dbx SelecterContext, dest interface{}, args ...interface{}) error {
+	q.track()
+	return dbx.SelectContext(ctx, dest, q.SQL, args...)
+}
+
+// Queryx is a wrapper for jmoiron/sqlx's Queryx().
+func (q Query) Queryx(dbx Queryerx, args ...interface{}) (*sqlx.Rows, error) {
+	q.track()
+	return dbx.Queryx(q.SQL, args...)
+}
+
+// QueryxContext is a wrapper for jmoiron/sqlx's QueryxContext().
+func (q Query) QueryxContext(ctx context.Context, dbx QueryerxContext, args ...interface{}) (*sqlx.Rows, error) {
+	q.track()
+	return dbx.QueryxContext(ctx, q.SQL, args...)
+}
+
+// QueryRowx is a wrapper for jmoiron/sqlx's QueryRowx().
+func (q Query) QueryRowx(dbx QueryRowerx, args ...interface{}) *sqlx.Row {
+	q.track()
+	return dbx.QueryRowx(q.SQL, args...)
+}
+
+// QueryRowxContext is a wrapper for jmoiron/sqlx's QueryRowxContext().
+func (q Query) QueryRowxContext(ctx context.Context, dbx QueryRowerxContext, args ...interface{}) *sqlx.Row {
+	q.track()
+	return dbx.QueryRowxContext(ctx, q.SQL, args...)
+}
+
+// Exec is a wrapper for database/sql's Exec().
+func (q Query) Exec(db dotsql.Execer, args ...interface{}) (sql.Result, error) {
+	q.track()
+	return db.Exec(q.SQL, args...)
+}
+
+// ExecContext is a wrapper for database/sql's ExecContext().
+func (q Query) ExecContext(ctx context.Context, db dotsql.ExecerContext, args ...interface{}) (sql.Result, error) {
+	q.track()
+	return db.ExecContext(ctx, q.SQL, args...)
+}
+
+// MustExec is a wrapper for jmoiron/sqlx's MustExec().
+func (q Query) MustExec(dbx MustExecer, args ...interface{}) sql.Result {
+	q.track()
+	return dbx.MustExec(q.SQL, args...)
+}
+
+// MustExecContext is a wrapper for jmoiron/sqlx's MustExecContext().
+func (q Query) MustExecContext(ctx context.Context, dbx MustExecerContext, args ...interface{}) sql.Result {
+	q.track()
+	return dbx.MustExecContext(ctx, q.SQL, args...)
+}
+
+// Rebind is a wrapper for jmoiron/sqlx's Rebind().
+func (q Query) Rebind(dbx Rebinder) string {
+	return dbx.Rebind(q.SQL)
+}
+
+// PrepareNamed is a wrapper for jmoiron/sqlx's PrepareNamed().
+func (q Query) PrepareNamed(dbx NamedPreparer) (*sqlx.NamedStmt, error) {
+	q.track()
+	return dbx.PrepareNamed(q.named())
+}
+
+// PrepareNamedContext is a wrapper for jmoiron/sqlx's PrepareNamedContext().
+func (q Query) PrepareNamedContext(ctx context.Context, dbx NamedPreparerContext) (*sqlx.NamedStmt, error) {
+	q.track()
+	return dbx.PrepareNamedContext(ctx, q.named())
+}
+
+// NamedQuery is a wrapper for jmoiron/sqlx's NamedQuery().
+func (q Query) NamedQuery(dbx NamedQueryer, arg interface{}) (*sqlx.Rows, error) {
+	q.track()
+	return dbx.NamedQuery(q.named(), arg)
+}
+
+// NamedQueryContext is a wrapper for jmoiron/sqlx's NamedQueryContext().
+func (q Query) NamedQueryContext(ctx context.Context, dbx NamedQueryerContext, arg interface{}) (*sqlx.Rows, error) {
+	q.track()
+	return dbx.NamedQueryContext(ctx, q.named(), arg)
+}
+
+// NamedExec is a wrapper for jmoiron/sqlx's NamedExec().
+func (q Query) NamedExec(dbx NamedExecer, arg interface{}) (sql.Result, error) {
+	q.track()
+	return dbx.NamedExec(q.named(), arg)
+}
+
+// NamedExecContext is a wrapper for jmoiron/sqlx's NamedExecContext().
+func (q Query) NamedExecContext(ctx context.Context, dbx NamedExecerContext, arg interface{}) (sql.Result, error) {
+	q.track()
+	return dbx.NamedExecContext(ctx, q.named(), arg)
+}
+
+// BindNamed is a wrapper for jmoiron/sqlx's BindNamed().
+func (q Query) BindNamed(dbx NamedBinder, arg interface{}) (string, []interface{}, error) {
+	return dbx.BindNamed(q.named(), arg)
+}
+
+// NamedGet binds arg to the query and executes it via jmoiron/sqlx's Get().
+func (q Query) NamedGet(dbx NamedGetter, dest interface{}, arg interface{}) error {
+	q.track()
+	query, args, err := dbx.BindNamed(q.named(), arg)
+	if err != nil {
+		return err
+	}
+
+	return dbx.Get(dest, query, args...)
+}
+
+// NamedGetContext binds arg to the query and executes it via jmoiron/sqlx's
+// GetContext().
+func (q Query) NamedGetContext(ctx context.Context, dbx NamedGetterContext, dest interface{}, arg interface{}) error {
+	q.track()
+	query, args, err := dbx.BindNamed(q.named(), arg)
+	if err != nil {
+		return err
+	}
+
+	return dbx.GetContext(ctx, dest, query, args...)
+}
+
+// NamedSelect binds arg to the query and executes it via jmoiron/sqlx's
+// Select().
+func (q Query) NamedSelect(dbx NamedSelecter, dest interface{}, arg interface{}) error {
+	q.track()
+	query, args, err := dbx.BindNamed(q.named(), arg)
+	if err != nil {
+		return err
+	}
+
+	return dbx.Select(dest, query, args...)
+}
+
+// NamedSelectContext binds arg to the query and executes it via
+// jmoiron/sqlx's SelectContext().
+func (q Query) NamedSelectContext(ctx context.Context, dbx NamedSelecterContext, dest interface{}, arg interface{}) error {
+	q.track()
+	query, args, err := dbx.BindNamed(q.named(), arg)
+	if err != nil {
+		return err
+	}
+
+	return dbx.SelectContext(ctx, dest, query, args...)
+}
+
+// In is a wrapper for jmoiron/sqlx's In(). Queries written, or rebound,
+// with `$1` or `@p1` bindvars keep their style.
+func (q Query) In(args ...interface{}) (string, []interface{}, error) {
+	return in(q.SQL, args...)
+}
+
+// track records the query in the tracker, if one is set.
+func (q Query) track() {
+	if q.tracker != nil {
+		q.tracker.Track(q.Name)
+	}
+}
+
+// named returns the SQL executed with named parameters, without comments,
+// whose colons jmoiron/sqlx would take for named parameters.
+func (q Query) named() string {
+	return stripComments(q.SQL)
+}
@@ -0,0 +1,166 @@
+package dotsqlx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	tr := NewTracker()
+	dot := newDot(t, `
+-- name: select
+-- tags: admin
+SELECT nr FROM numbers WHERE nr = ?`).WithTracker(tr)
+
+	// query not found
+	q, err := dot.Lookup("select123")
+	assert.Zero(t, q)
+	assert.NotNil(t, err)
+
+	// successful call
+	q, err = dot.Lookup("select")
+	require.Nil(t, err)
+	assert.Equal(t, "select", q.Name)
+	assert.Equal(t, "-- tags: admin\nSELECT nr FROM numbers WHERE nr = ?", q.SQL)
+	assert.Equal(t, Annotations{{Key: "tags", Value: "admin"}}, q.Annotations)
+	assert.Empty(t, tr.Executed())
+
+	// executions recorded
+	db := newDB(t, 3)
+	defer db.Close()
+
+	var nr int
+	require.Nil(t, q.Get(db, &nr, 1))
+	require.Nil(t, q.Get(db, &nr, 2))
+	assert.Equal(t, map[string]int{"select": 2}, tr.Executed())
+
+	// calls returning the SQL not recorded
+	q.Rebind(db)
+	_, _, err = q.In(1)
+	require.Nil(t, err)
+
+	_, err = dot.Raw("select")
+	require.Nil(t, err)
+
+	_, _, err = dot.In("select", 1)
+	require.Nil(t, err)
+
+	require.Nil(t, dot.Get(db, &nr, "select", 2))
+	assert.Equal(t, map[string]int{"select": 3}, tr.Executed())
+}
+
+func TestMustLookup(t *testing.T) {
+	dot := newDot(t, queries)
+
+	// query not found
+	assert.Panics(t, func() {
+		dot.MustLookup("select123")
+	})
+
+	// successful call
+	assert.NotPanics(t, func() {
+		q := dot.MustLookup("select")
+		assert.Equal(t, "select", q.Name)
+	})
+}
+
+func TestQueryWrappers(t *testing.T) {
+	q := newDot(t, queries).MustLookup("select")
+	ctx := context.Background()
+
+	g := &GetterContextMock{
+		GetContextFunc: func(_ context.Context, _ interface{}, _ string, _ ...interface{}) error {
+			return nil
+		},
+	}
+	assert.Nil(t, q.GetContext(ctx, g, &number{}, 1))
+	require.Len(t, g.GetContextCalls(), 1)
+	assert.Equal(t, q.SQL, g.GetContextCalls()[0].Query)
+	assert.Equal(t, []interface{}{1}, g.GetContextCalls()[0].Args)
+
+	s := &SelecterMock{
+		SelectFunc: func(_ interface{}, _ string, _ ...interface{}) error {
+			return assert.AnError
+		},
+	}
+	assert.Equal(t, assert.AnError, q.Select(s, &[]number{}, 1))
+	require.Len(t, s.SelectCalls(), 1)
+	assert.Equal(t, q.SQL, s.SelectCalls()[0].Query)
+
+	r := &QueryRowerxMock{
+		QueryRowxFunc: func(_ string, _ ...interface{}) *sqlx.Row {
+			return &sqlx.Row{}
+		},
+	}
+	assert.NotNil(t, q.QueryRowx(r, 1))
+	require.Len(t, r.QueryRowxCalls(), 1)
+	assert.Equal(t, q.SQL, r.QueryRowxCalls()[0].Query)
+
+	e := &MustExecerContextMock{
+		MustExecContextFunc: func(_ context.Context, _ string, _ ...interface{}) sql.Result {
+			return sqlResult{}
+		},
+	}
+	assert.NotNil(t, q.MustExecContext(ctx, e, 1))
+	require.Len(t, e.MustExecContextCalls(), 1)
+	assert.Equal(t, q.SQL, e.MustExecContextCalls()[0].Query)
+
+	b := &NamedBinderMock{
+		BindNamedFunc: func(query string, _ interface{}) (string, []interface{}, error) {
+			return query, nil, nil
+		},
+	}
+	res, _, err := q.BindNamed(b, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, q.SQL, res)
+
+	res, args, err := q.In([]int{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT nr FROM numbers WHERE nr = ?, ?", res)
+	assert.Equal(t, []interface{}{1, 2}, args)
+}
+
+func TestQueryNamed(t *testing.T) {
+	dot := newDot(t, namedQueries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	q := dot.MustLookup("select_named")
+	arg := map[string]interface{}{"min": 1}
+
+	var nr int
+	assert.Nil(t, q.NamedGet(db, &nr, arg))
+	assert.Equal(t, 1, nr)
+	assert.Equal(t, sql.ErrNoRows, q.NamedGetContext(context.Background(), db, &nr, map[string]interface{}{"min": 5}))
+
+	var nrs []int
+	assert.Nil(t, q.NamedSelectContext(context.Background(), db, &nrs, arg))
+	assert.Equal(t, []int{1, 2}, nrs)
+
+	// comments are not taken for named parameters
+	insDot := newDot(t, `
+-- name: insert
+-- description: inserts a number
+INSERT INTO numbers (nr) -- note: one row
+VALUES (:nr)`)
+	ins := insDot.MustLookup("insert")
+
+	res, err := ins.NamedExecContext(context.Background(), db, number{Nr: 3})
+	require.Nil(t, err)
+	n, err := res.RowsAffected()
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+
+	_, err = insDot.NamedExec(db, "insert", number{Nr: 4})
+	require.Nil(t, err)
+	db.MustExec("DELETE FROM numbers WHERE nr = 4")
+
+	nrs = nil
+	assert.Nil(t, q.NamedSelect(db, &nrs, arg))
+	assert.Equal(t, []int{1, 2, 3}, nrs)
+}
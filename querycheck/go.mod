@@ -0,0 +1,23 @@
+module github.com/swithek/dotsqlx/querycheck
+
+go 1.23.0
+
+require (
+	github.com/jmoiron/sqlx v1.2.0
+	github.com/stretchr/testify v1.5.1
+	github.com/swithek/dotsqlx v0.0.0-00010101000000-000000000000
+	golang.org/x/tools v0.35.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/qustavo/dotsql v1.1.0 // indirect
+	golang.org/x/mod v0.26.0 // indirect
+	golang.org/x/sync v0.16.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The library is used from this repository until it is released.
+replace github.com/swithek/dotsqlx => ../
@@ -0,0 +1,273 @@
+// Package querycheck defines an analyzer that checks the query names passed
+// to DotSqlx, dotsql and StmtCache methods.
+//
+// Calls with constant query names are matched against the queries loaded
+// from the .sql files given by the -queries flag. Unknown names are
+// reported, as well as names whose statement doesn't match the operation,
+// e.g. Select called with an INSERT query or Exec with a SELECT query.
+package querycheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/swithek/dotsqlx"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// Analyzer reports unknown and mismatching dotsql query names.
+var Analyzer = &analysis.Analyzer{
+	Name:     "dotsqlx",
+	Doc:      "check query names passed to dotsqlx methods",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// queries holds the value of the -queries flag.
+var queries string
+
+func init() {
+	Analyzer.Flags.StringVar(&queries, "queries", "", "comma separated list of .sql files or glob patterns")
+}
+
+// targets holds the types whose methods are checked, keyed by their
+// package path.
+var targets = map[string]map[string]bool{
+	"github.com/swithek/dotsqlx": {"DotSqlx": true, "StmtCache": true},
+	"github.com/qustavo/dotsql":  {"DotSql": true},
+}
+
+// rowMethods hold the methods that expect queries returning rows.
+var rowMethods = map[string]bool{
+	"Get":                true,
+	"GetContext":         true,
+	"Select":             true,
+	"SelectContext":      true,
+	"Queryx":             true,
+	"QueryxContext":      true,
+	"QueryRowx":          true,
+	"QueryRowxContext":   true,
+	"Query":              true,
+	"QueryContext":       true,
+	"QueryRow":           true,
+	"QueryRowContext":    true,
+	"NamedGet":           true,
+	"NamedGetContext":    true,
+	"NamedSelect":        true,
+	"NamedSelectContext": true,
+	"NamedQuery":         true,
+	"NamedQueryContext":  true,
+	"SelectInChunks":     true,
+}
+
+// execMethods hold the methods that expect queries not returning rows.
+var execMethods = map[string]bool{
+	"Exec":             true,
+	"ExecContext":      true,
+	"MustExec":         true,
+	"MustExecContext":  true,
+	"NamedExec":        true,
+	"NamedExecContext": true,
+	"ExecInChunks":     true,
+	"NamedExecBatch":   true,
+}
+
+// execKeywords hold the statements that don't return rows unless they
+// have a RETURNING clause.
+var execKeywords = map[string]bool{
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"REPLACE":  true,
+	"MERGE":    true,
+	"CREATE":   true,
+	"DROP":     true,
+	"ALTER":    true,
+	"TRUNCATE": true,
+}
+
+var (
+	keywordRe   = regexp.MustCompile(`^\s*([A-Za-z]+)`)
+	returningRe = regexp.MustCompile(`(?i)\bRETURNING\b`)
+)
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if queries == "" {
+		return nil, nil
+	}
+
+	qm, err := load(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		fn, arg, name, ok := callSite(pass.TypesInfo, n.(*ast.CallExpr))
+		if !ok {
+			return
+		}
+
+		query, ok := qm[name]
+		if !ok {
+			pass.Reportf(arg.Pos(), "unknown query %q", name)
+			return
+		}
+
+		switch kw := keyword(query); {
+		case rowMethods[fn.Name()] && execKeywords[kw] && !returningRe.MatchString(query):
+			pass.Reportf(arg.Pos(), "%s called with %s query %q that returns no rows", fn.Name(), kw, name)
+		case execMethods[fn.Name()] && kw == "SELECT":
+			pass.Reportf(arg.Pos(), "%s called with SELECT query %q", fn.Name(), name)
+		}
+	})
+
+	return nil, nil
+}
+
+// callSite returns the called method, the query name argument and its
+// constant value if call is a call of a checked method with a constant
+// query name.
+func callSite(info *types.Info, call *ast.CallExpr) (*types.Func, ast.Expr, string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil, nil, "", false
+	}
+
+	s := info.Selections[sel]
+	if s == nil || s.Kind() != types.MethodVal {
+		return nil, nil, "", false
+	}
+
+	fn := s.Obj().(*types.Func)
+	if !isTarget(fn) {
+		return nil, nil, "", false
+	}
+
+	i := nameParam(fn.Type().(*types.Signature))
+	if i < 0 || i >= len(call.Args) {
+		return nil, nil, "", false
+	}
+
+	tv := info.Types[call.Args[i]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return nil, nil, "", false
+	}
+
+	return fn, call.Args[i], constant.StringVal(tv.Value), true
+}
+
+// isTarget reports whether fn is a method of one of the checked types.
+func isTarget(fn *types.Func) bool {
+	if fn.Pkg() == nil {
+		return false
+	}
+
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+
+	return targets[fn.Pkg().Path()][named.Obj().Name()]
+}
+
+// nameParam returns the index of the string param called name or -1 if
+// there is none.
+func nameParam(sig *types.Signature) int {
+	for i := 0; i < sig.Params().Len(); i++ {
+		p := sig.Params().At(i)
+		if p.Name() == "name" && types.Identical(p.Type(), types.Typ[types.String]) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// keyword returns the upper cased first keyword of the query, skipping
+// comment lines.
+func keyword(query string) string {
+	for _, line := range strings.Split(query, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+
+		if m := keywordRe.FindStringSubmatch(line); m != nil {
+			return strings.ToUpper(m[1])
+		}
+
+		return ""
+	}
+
+	return ""
+}
+
+var (
+	loadMu sync.Mutex
+	loaded = make(map[string]map[string]string)
+)
+
+// load returns the queries of all files matching the comma separated
+// patterns. Loaded queries are cached per patterns value since the
+// analyzer runs once per package.
+func load(patterns string) (map[string]string, error) {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	if qm, ok := loaded[patterns]; ok {
+		return qm, nil
+	}
+
+	var files []string
+	for _, p := range strings.Split(patterns, ",") {
+		ff, err := filepath.Glob(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, ff...)
+	}
+
+	// the files are loaded in the given order, so that later files
+	// override earlier ones as they do with dotsqlx.Load
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no query files match %q", patterns)
+	}
+
+	dot, err := dotsqlx.Load(files...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Queries that only have dialect-specific variants are checked
+	// against their first variant.
+	qm := make(map[string]string)
+	for _, d := range dot.Definitions() {
+		if _, ok := qm[d.Name]; !ok {
+			qm[d.Name] = d.SQL
+		}
+	}
+
+	loaded[patterns] = qm
+
+	return qm, nil
+}
@@ -0,0 +1,48 @@
+package querycheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	require.Nil(t, Analyzer.Flags.Set("queries", "testdata/*.sql"))
+	defer Analyzer.Flags.Set("queries", "")
+
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a")
+}
+
+func TestKeyword(t *testing.T) {
+	assert.Equal(t, "SELECT", keyword("-- tags: a\n\n  select 1"))
+	assert.Zero(t, keyword("-- tags: a"))
+	assert.Zero(t, keyword("(SELECT 1)"))
+}
+
+func TestLoad(t *testing.T) {
+	qm, err := load("testdata/*.sql, testdata/missing.sql")
+	require.Nil(t, err)
+	assert.Len(t, qm, 3)
+
+	_, err = load("[")
+	assert.NotNil(t, err)
+
+	// no files
+	_, err = load("testdata/missing.sql")
+	assert.NotNil(t, err)
+
+	// the given file order kept
+	qm, err = load("../testdata/overrides.sql,../testdata/includes.sql")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT nr FROM numbers", qm["select_nr"])
+
+	// includes and overrides resolved
+	qm, err = load("../testdata/includes.sql,../testdata/overrides.sql")
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"select_even": "SELECT\nnr,\nnr * 2 AS double\nFROM numbers\nWHERE nr % 2 = 0",
+		"select_nr":   "SELECT nr FROM numbers ORDER BY nr DESC",
+	}, qm)
+}
@@ -0,0 +1,63 @@
+package querycheck
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// References loads the Go packages matching the patterns, relative to dir,
+// and returns the positions of all checked method calls per constant query
+// name.
+func References(dir string, patterns ...string) (map[string][]token.Position, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedSyntax,
+		Dir:  dir,
+	}
+
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, errors.New("querycheck: packages contain errors")
+	}
+
+	refs := make(map[string][]token.Position)
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Syntax {
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+
+				if _, arg, name, ok := callSite(pkg.TypesInfo, call); ok {
+					refs[name] = append(refs[name], pkg.Fset.Position(arg.Pos()))
+				}
+
+				return true
+			})
+		}
+	}
+
+	return refs, nil
+}
+
+// Unused returns the sorted names that are not referenced.
+func Unused(names []string, refs map[string][]token.Position) []string {
+	var res []string
+	for _, name := range names {
+		if len(refs[name]) == 0 {
+			res = append(res, name)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
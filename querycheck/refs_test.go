@@ -0,0 +1,35 @@
+package querycheck
+
+import (
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferences(t *testing.T) {
+	// invalid packages
+	refs, err := References("testdata", "./missing")
+	assert.Nil(t, refs)
+	assert.NotNil(t, err)
+
+	// successful call
+	refs, err = References("testdata", "./refs")
+	require.Nil(t, err)
+	require.Len(t, refs, 3)
+	require.Len(t, refs["select_users"], 2)
+	assert.Equal(t, 14, refs["select_users"][0].Line)
+	assert.Equal(t, 15, refs["select_users"][1].Line)
+	assert.Len(t, refs["insert_user"], 1)
+	assert.Len(t, refs["missing"], 1)
+}
+
+func TestUnused(t *testing.T) {
+	refs := map[string][]token.Position{
+		"select_users": {{Line: 1}},
+	}
+
+	assert.Equal(t, []string{"delete_user", "insert_user"}, Unused([]string{"select_users", "insert_user", "delete_user"}, refs))
+	assert.Nil(t, Unused([]string{"select_users"}, refs))
+}
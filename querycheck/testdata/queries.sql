@@ -0,0 +1,9 @@
+-- name: select_users
+SELECT * FROM users
+
+-- name: insert_user
+-- tags: admin
+INSERT INTO users (name) VALUES (?)
+
+-- name: insert_user_returning
+INSERT INTO users (name) VALUES (?) RETURNING id
@@ -0,0 +1,19 @@
+package refs
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swithek/dotsqlx"
+)
+
+const usersQuery = "select_users"
+
+func f(ctx context.Context, dot *dotsqlx.DotSqlx, db *sqlx.DB, name string) {
+	var nrs []int
+	dot.Select(db, &nrs, usersQuery)
+	dot.SelectContext(ctx, db, &nrs, "select_users")
+	dot.Exec(db, "insert_user", 1)
+	dot.Exec(db, name)
+	dot.MustLookup("missing")
+}
@@ -0,0 +1,22 @@
+package a
+
+import "github.com/swithek/dotsqlx"
+
+type other struct{}
+
+func (o other) Select(dbx interface{}, dest interface{}, name string) {}
+
+const usersQuery = "select_users"
+
+func f(dot *dotsqlx.DotSqlx, c *dotsqlx.StmtCache, name string) {
+	dot.Select(nil, nil, usersQuery)
+	dot.Select(nil, nil, "select_user") // want `unknown query "select_user"`
+	dot.Get(nil, nil, "insert_user", 1) // want `Get called with INSERT query "insert_user" that returns no rows`
+	dot.Get(nil, nil, "insert_user_returning", 1)
+	dot.MustExec(nil, "insert_user", 1)
+	dot.MustExec(nil, "select_users") // want `MustExec called with SELECT query "select_users"`
+	dot.In("missing")                 // want `unknown query "missing"`
+	dot.Select(nil, nil, name)
+	c.SelectContext(nil, nil, nil, "missing") // want `unknown query "missing"`
+	other{}.Select(nil, nil, "missing")
+}
@@ -0,0 +1,21 @@
+package dotsqlx
+
+type DotSqlx struct{}
+
+func (d DotSqlx) Get(dbx interface{}, dest interface{}, name string, args ...interface{}) error {
+	return nil
+}
+
+func (d DotSqlx) Select(dbx interface{}, dest interface{}, name string, args ...interface{}) error {
+	return nil
+}
+
+func (d DotSqlx) MustExec(dbx interface{}, name string, args ...interface{}) {}
+
+func (d DotSqlx) In(name string, args ...interface{}) {}
+
+type StmtCache struct{}
+
+func (c *StmtCache) SelectContext(ctx interface{}, db interface{}, dest interface{}, name string, args ...interface{}) error {
+	return nil
+}
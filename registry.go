@@ -0,0 +1,161 @@
+package dotsqlx
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registry is a set of named queries that can be changed at runtime. It is
+// safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	queries map[string]Query
+}
+
+// NewRegistry creates a new empty Registry instance.
+func NewRegistry() *Registry {
+	return &Registry{queries: make(map[string]Query)}
+}
+
+// WrapRegistry creates a new DotSqlx instance using the queries of the
+// registry. Changes made to the registry are visible to DotSqlx, but not to
+// the copies returned by RebindAll, Normalize and WithVariants, which hold
+// a snapshot of the queries.
+func WrapRegistry(r *Registry) *DotSqlx {
+	return WrapSource(r)
+}
+
+// Add registers a new query. The annotations of the query are its leading
+// `-- key: value` lines followed by meta. An error is returned if a query
+// with the same name exists.
+func (r *Registry) Add(name, query string, meta Annotations) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.queries[name]; ok {
+		return fmt.Errorf("dotsqlx: '%s' already exists", name)
+	}
+
+	r.queries[name] = newQuery(name, query, meta)
+	return nil
+}
+
+// AddDefinitions registers the definitions, e.g. the ones read from dotsql
+// files and catalogs. An error is returned for the first definition whose
+// name is already registered; the definitions preceding it stay
+// registered.
+func (r *Registry) AddDefinitions(dd []Definition) error {
+	for _, d := range dd {
+		if err := r.Add(d.Name, d.SQL, nil); err != nil {
+			return fmt.Errorf("dotsqlx: %s: %s: already exists", d.Location(), d.Name)
+		}
+	}
+
+	return nil
+}
+
+// Replace changes the SQL and metadata of an existing query. An error is
+// returned if the query does not exist.
+func (r *Registry) Replace(name, query string, meta Annotations) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.queries[name]; !ok {
+		return &QueryError{Name: name, Err: ErrNotFound}
+	}
+
+	r.queries[name] = newQuery(name, query, meta)
+	return nil
+}
+
+// Remove unregisters the query. False is returned if it does not exist.
+func (r *Registry) Remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.queries[name]; !ok {
+		return false
+	}
+
+	delete(r.queries, name)
+	return true
+}
+
+// Has reports whether the query exists.
+func (r *Registry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.queries[name]
+	return ok
+}
+
+// Get returns the query and reports whether it exists.
+func (r *Registry) Get(name string) (Query, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	q, ok := r.queries[name]
+	return q, ok
+}
+
+// Raw returns the SQL of the query.
+func (r *Registry) Raw(name string) (string, error) {
+	q, ok := r.Get(name)
+	if !ok {
+		return "", &QueryError{Name: name, Err: ErrNotFound}
+	}
+
+	return q.SQL, nil
+}
+
+// Names returns the sorted names of all queries.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.queries))
+	for name := range r.queries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// QueryMap returns a copy of the SQL of all queries, keyed by name.
+func (r *Registry) QueryMap() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	qm := make(map[string]string, len(r.queries))
+	for name, q := range r.queries {
+		qm[name] = q.SQL
+	}
+
+	return qm
+}
+
+// Snapshot returns an independent copy of the registry.
+func (r *Registry) Snapshot() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s := &Registry{queries: make(map[string]Query, len(r.queries))}
+	for name, q := range r.queries {
+		s.queries[name] = q
+	}
+
+	return s
+}
+
+// newQuery creates a Query with the leading annotations of its SQL
+// followed by meta.
+func newQuery(name, query string, meta Annotations) Query {
+	aa := ParseAnnotations(query)
+	aa = append(aa[:len(aa):len(aa)], meta...)
+
+	return Query{Name: name, SQL: query, Annotations: aa}
+}
@@ -0,0 +1,124 @@
+package dotsqlx
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	assert.False(t, r.Has("select"))
+
+	require.Nil(t, r.Add("select", "-- tags: a\nSELECT 1", Annotations{{Key: "owner", Value: "team"}}))
+	assert.EqualError(t, r.Add("select", "SELECT 2", nil), "dotsqlx: 'select' already exists")
+	assert.True(t, r.Has("select"))
+
+	q, ok := r.Get("select")
+	require.True(t, ok)
+	assert.Equal(t, Query{
+		Name: "select",
+		SQL:  "-- tags: a\nSELECT 1",
+		Annotations: Annotations{
+			{Key: "tags", Value: "a"},
+			{Key: "owner", Value: "team"},
+		},
+	}, q)
+
+	snap := r.Snapshot()
+
+	assert.Equal(t, &QueryError{Name: "missing", Err: ErrNotFound}, r.Replace("missing", "SELECT 1", nil))
+	require.Nil(t, r.Replace("select", "SELECT 2", nil))
+	require.Nil(t, r.Add("delete", "DELETE FROM t", nil))
+
+	query, err := r.Raw("select")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT 2", query)
+
+	_, err = r.Raw("missing")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []string{"delete", "select"}, r.Names())
+	assert.Equal(t, map[string]string{"delete": "DELETE FROM t", "select": "SELECT 2"}, r.QueryMap())
+	assert.Equal(t, map[string]string{"select": "-- tags: a\nSELECT 1"}, snap.QueryMap())
+
+	assert.True(t, r.Remove("delete"))
+	assert.False(t, r.Remove("delete"))
+	assert.Equal(t, []string{"select"}, r.Names())
+}
+
+func TestWrapRegistry(t *testing.T) {
+	r := NewRegistry()
+	require.Nil(t, r.Add("select_nr", "SELECT nr FROM numbers WHERE nr = ?", Annotations{{Key: "tags", Value: "x"}}))
+
+	dot := WrapRegistry(r)
+	db := newDB(t, 3)
+
+	var nr int
+	require.Nil(t, dot.Get(db, &nr, "select_nr", 2))
+	assert.Equal(t, 2, nr)
+
+	aa, err := dot.Annotations("select_nr")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"x"}, aa.Tags())
+
+	q, err := dot.Lookup("select_nr")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"x"}, q.Annotations.Tags())
+
+	// rebound copies hold a snapshot
+	rebound, err := dot.RebindAll(sqlx.DOLLAR)
+	require.Nil(t, err)
+	require.Nil(t, r.Add("select_one", "SELECT 1", nil))
+
+	_, err = dot.Raw("select_one")
+	assert.Nil(t, err)
+
+	_, err = rebound.Raw("select_one")
+	assert.NotNil(t, err)
+	assert.True(t, r.Remove("select_one"))
+
+	require.Nil(t, r.Replace("select_nr", "SELECT nr + 10 FROM numbers WHERE nr = ?", nil))
+	require.Nil(t, dot.Get(db, &nr, "select_nr", 2))
+	assert.Equal(t, 12, nr)
+
+	r.Remove("select_nr")
+	assert.NotNil(t, dot.Get(db, &nr, "select_nr", 2))
+	assert.Empty(t, dot.Names())
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	r := NewRegistry()
+	dot := WrapRegistry(r)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			name := fmt.Sprintf("q%d", i)
+			assert.Nil(t, r.Add(name, "SELECT 1", nil))
+			assert.Nil(t, r.Replace(name, "SELECT 2", nil))
+			r.Remove(name)
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			for _, name := range dot.Names() {
+				dot.Raw(name)
+			}
+
+			r.Snapshot()
+		}()
+	}
+
+	wg.Wait()
+	assert.Empty(t, r.Names())
+}
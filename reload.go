@@ -0,0 +1,169 @@
+package dotsqlx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReloadInterval is the polling interval used when none is set.
+const DefaultReloadInterval = time.Second
+
+// ValidationError is returned when loaded query files have issues.
+type ValidationError struct {
+	Issues []Issue
+}
+
+// Error returns all issues, one per line.
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dotsqlx: %d issues found", len(e.Issues))
+
+	for _, i := range e.Issues {
+		b.WriteString("\n\t")
+		b.WriteString(i.String())
+	}
+
+	return b.String()
+}
+
+// ReloadOptions holds the options of a Reloader.
+type ReloadOptions struct {
+	// Interval is the polling interval of Run. Defaults to
+	// DefaultReloadInterval.
+	Interval time.Duration
+
+	// Transform, if set, is applied to every loaded set of queries before
+	// it is swapped in, e.g. to rebind queries or set a tracker.
+	Transform func(*DotSqlx) (*DotSqlx, error)
+
+	// OnError, if set, is called by Run when changed files cannot be
+	// loaded, once per change. The previous set of queries is kept.
+	OnError func(error)
+}
+
+// fileStamp is used to detect file changes.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+func (s fileStamp) equal(o fileStamp) bool {
+	return s.mod.Equal(o.mod) && s.size == o.size
+}
+
+// Reloader keeps the queries of files up to date. The files are loaded
+// with Load and validated with Validate; a new set of queries is swapped
+// in only if both succeed, so callers of Current never see a partially
+// loaded or invalid set.
+type Reloader struct {
+	files []string
+	opts  ReloadOptions
+
+	mu     sync.Mutex
+	stamps []fileStamp
+	cur    atomic.Value
+}
+
+// NewReloader loads the files and creates a new Reloader instance.
+func NewReloader(opts ReloadOptions, files ...string) (*Reloader, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultReloadInterval
+	}
+
+	r := &Reloader{files: files, opts: opts}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Current returns the most recently loaded set of queries.
+func (r *Reloader) Current() *DotSqlx {
+	return r.cur.Load().(*DotSqlx)
+}
+
+// Reload loads the files again if any of them changed, or was removed,
+// since the last load. True is returned if a new set of queries was
+// swapped in. Files that failed to load are not loaded again until they
+// change, so that the same error isn't returned on every call.
+func (r *Reloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamps := make([]fileStamp, len(r.files))
+	changed := len(r.stamps) == 0
+
+	for i, file := range r.files {
+		fi, err := os.Stat(file)
+		switch {
+		case err == nil:
+			stamps[i] = fileStamp{mod: fi.ModTime(), size: fi.Size()}
+		case !os.IsNotExist(err):
+			return false, err
+		}
+
+		changed = changed || !stamps[i].equal(r.stamps[i])
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	r.stamps = stamps
+
+	d, err := r.load()
+	if err != nil {
+		return false, err
+	}
+
+	r.cur.Store(d)
+	return true, nil
+}
+
+// Run polls the files until the context is cancelled, reloading them when
+// they change.
+func (r *Reloader) Run(ctx context.Context) {
+	t := time.NewTicker(r.opts.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		if _, err := r.Reload(); err != nil && r.opts.OnError != nil {
+			r.opts.OnError(err)
+		}
+	}
+}
+
+// load loads, validates and transforms the queries of the files.
+func (r *Reloader) load() (*DotSqlx, error) {
+	dd, err := LoadDefinitions(r.files...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ii := Validate(dd); len(ii) > 0 {
+		return nil, &ValidationError{Issues: ii}
+	}
+
+	d, err := FromDefinitions(dd)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.opts.Transform != nil {
+		return r.opts.Transform(d)
+	}
+
+	return d, nil
+}
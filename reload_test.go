@@ -0,0 +1,167 @@
+package dotsqlx
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeQueries(t *testing.T, file, q string, mod time.Time) {
+	require.Nil(t, ioutil.WriteFile(file, []byte(q), 0644))
+	require.Nil(t, os.Chtimes(file, mod, mod))
+}
+
+func TestReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotsqlx")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = NewReloader(ReloadOptions{}, filepath.Join(dir, "missing.sql"))
+	assert.NotNil(t, err)
+
+	file := filepath.Join(dir, "q.sql")
+	mod := time.Now().Add(-time.Hour)
+	writeQueries(t, file, "-- name: select\nSELECT ?", mod)
+
+	r, err := NewReloader(ReloadOptions{
+		Transform: func(d *DotSqlx) (*DotSqlx, error) {
+			return d.RebindAll(sqlx.DOLLAR)
+		},
+	}, file)
+	require.Nil(t, err)
+
+	old := r.Current()
+	q, err := old.Raw("select")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT $1", q)
+
+	// unchanged files
+	ok, err := r.Reload()
+	require.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, old, r.Current())
+
+	// invalid files
+	writeQueries(t, file, "-- name: select\nSELECT 'a", mod.Add(time.Minute))
+
+	ok, err = r.Reload()
+	require.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, "dotsqlx: 1 issues found\n\t"+file+":1: select: unbalanced quotes or comments", err.Error())
+	assert.False(t, ok)
+	assert.Equal(t, old, r.Current())
+
+	// valid change
+	writeQueries(t, file, "-- name: select\nSELECT ?, ?", mod.Add(2*time.Minute))
+
+	ok, err = r.Reload()
+	require.Nil(t, err)
+	assert.True(t, ok)
+
+	q, err = r.Current().Raw("select")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT $1, $2", q)
+
+	q, err = old.Raw("select")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT $1", q)
+
+	// failed load not retried until files change
+	writeQueries(t, file, "-- name: select\n", mod.Add(3*time.Minute))
+
+	_, err = r.Reload()
+	assert.NotNil(t, err)
+
+	ok, err = r.Reload()
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// removed files
+	require.Nil(t, os.Remove(file))
+
+	_, err = r.Reload()
+	assert.NotNil(t, err)
+
+	ok, err = r.Reload()
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	writeQueries(t, file, "-- name: select\nSELECT 1", mod)
+
+	ok, err = r.Reload()
+	require.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestReloaderCatalog(t *testing.T) {
+	r, err := NewReloader(ReloadOptions{}, "testdata/catalog.yaml")
+	require.Nil(t, err)
+
+	aa, err := r.Current().Annotations("select_numbers")
+	require.Nil(t, err)
+	assert.Equal(t, "5s", aa.Get("timeout"))
+}
+
+func TestReloaderOverrides(t *testing.T) {
+	r, err := NewReloader(ReloadOptions{}, "testdata/includes.sql", "testdata/overrides.sql")
+	require.Nil(t, err)
+
+	q, err := r.Current().Raw("select_nr")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT nr FROM numbers ORDER BY nr DESC", q)
+}
+
+func TestReloaderRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotsqlx")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "q.sql")
+	mod := time.Now().Add(-time.Hour)
+	writeQueries(t, file, "-- name: select\nSELECT 1", mod)
+
+	errs := make(chan error, 1)
+	r, err := NewReloader(ReloadOptions{
+		Interval: time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}, file)
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.Run(ctx)
+	}()
+
+	writeQueries(t, file, "-- name: select\nSELECT 2", mod.Add(time.Minute))
+	assert.Eventually(t, func() bool {
+		q, _ := r.Current().Raw("select")
+		return q == "SELECT 2"
+	}, time.Second, time.Millisecond)
+
+	writeQueries(t, file, "-- name: select\n", mod.Add(2*time.Minute))
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("error not reported")
+	}
+
+	cancel()
+	wg.Wait()
+}
@@ -0,0 +1,142 @@
+package dotsqlx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/qustavo/dotsql"
+)
+
+// ErrNotFound is returned, wrapped in a *QueryError, when a named query
+// does not exist.
+var ErrNotFound = errors.New("dotsqlx: query not found")
+
+// Source provides named queries. *dotsql.DotSql and *Registry implement
+// it.
+type Source interface {
+	// Raw returns the SQL of the named query.
+	Raw(name string) (string, error)
+
+	// QueryMap returns the SQL of all queries, keyed by name.
+	QueryMap() map[string]string
+}
+
+// annotatedSource is implemented by sources that hold query annotations
+// besides the leading lines of the SQL, e.g. *Registry.
+type annotatedSource interface {
+	Get(name string) (Query, bool)
+}
+
+// WrapSource creates a new DotSqlx instance using the queries of the
+// source.
+func WrapSource(s Source) *DotSqlx {
+	d, ok := s.(*dotsql.DotSql)
+	if !ok {
+		d = &dotsql.DotSql{}
+	}
+
+	return &DotSqlx{DotSql: d, source: s}
+}
+
+// MapSource is a Source backed by a map of SQL keyed by query name, e.g.
+// one defined in a generated Go file.
+type MapSource map[string]string
+
+// Raw returns the SQL of the named query.
+func (s MapSource) Raw(name string) (string, error) {
+	query, ok := s[name]
+	if !ok {
+		return "", &QueryError{Name: name, Err: ErrNotFound}
+	}
+
+	return query, nil
+}
+
+// QueryMap returns the map itself.
+func (s MapSource) QueryMap() map[string]string {
+	return s
+}
+
+// LoadSource executes the query, which must return the name and the SQL of
+// named queries as its first two columns, e.g.
+// `SELECT name, query FROM queries`, and returns the loaded queries.
+func LoadSource(ctx context.Context, db QueryerxContext, query string) (MapSource, error) {
+	rows, err := db.QueryxContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s := make(MapSource)
+	for rows.Next() {
+		var name, sql string
+		if err := rows.Scan(&name, &sql); err != nil {
+			return nil, err
+		}
+
+		s[name] = sql
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// compositeSource looks queries up in multiple sources.
+type compositeSource []Source
+
+// Composite returns a Source that looks queries up in all sources, in
+// order; a query of an earlier source takes precedence over queries with
+// the same name in later sources.
+func Composite(ss ...Source) Source {
+	return compositeSource(ss)
+}
+
+// Raw returns the SQL of the named query from the first source that has
+// it. The error of the last source is returned if none does.
+func (c compositeSource) Raw(name string) (string, error) {
+	var err error = &QueryError{Name: name, Err: ErrNotFound}
+
+	for _, s := range c {
+		var query string
+		if query, err = s.Raw(name); err == nil {
+			return query, nil
+		}
+	}
+
+	return "", err
+}
+
+// QueryMap returns the SQL of all queries of all sources.
+func (c compositeSource) QueryMap() map[string]string {
+	qm := make(map[string]string)
+	for i := len(c) - 1; i >= 0; i-- {
+		for name, query := range c[i].QueryMap() {
+			qm[name] = query
+		}
+	}
+
+	return qm
+}
+
+// Get returns the query, with annotations if the source holding it keeps
+// them.
+func (c compositeSource) Get(name string) (Query, bool) {
+	for _, s := range c {
+		if as, ok := s.(annotatedSource); ok {
+			if q, ok := as.Get(name); ok {
+				return q, true
+			}
+
+			continue
+		}
+
+		if query, err := s.Raw(name); err == nil {
+			return Query{Name: name, SQL: query, Annotations: ParseAnnotations(query)}, true
+		}
+	}
+
+	return Query{}, false
+}
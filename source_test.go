@@ -0,0 +1,104 @@
+package dotsqlx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/qustavo/dotsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapSource(t *testing.T) {
+	s := MapSource{"select": "SELECT 1"}
+
+	q, err := s.Raw("select")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT 1", q)
+
+	_, err = s.Raw("missing")
+	assert.Equal(t, &QueryError{Name: "missing", Err: ErrNotFound}, err)
+
+	assert.Equal(t, map[string]string{"select": "SELECT 1"}, s.QueryMap())
+}
+
+func TestLoadSource(t *testing.T) {
+	db := newDB(t, 0)
+	ctx := context.Background()
+
+	_, err := LoadSource(ctx, db, "SELECT name, query FROM missing")
+	assert.NotNil(t, err)
+
+	_, err = LoadSource(ctx, db, "SELECT 1")
+	assert.NotNil(t, err)
+
+	db.MustExec("CREATE TABLE queries (name TEXT, query TEXT)")
+	db.MustExec("INSERT INTO queries VALUES ('count', 'SELECT COUNT(*) FROM numbers')")
+
+	s, err := LoadSource(ctx, db, "SELECT name, query FROM queries")
+	require.Nil(t, err)
+	assert.Equal(t, MapSource{"count": "SELECT COUNT(*) FROM numbers"}, s)
+
+	var n int
+	require.Nil(t, WrapSource(s).GetContext(ctx, db, &n, "count"))
+	assert.Equal(t, 0, n)
+}
+
+func TestComposite(t *testing.T) {
+	d, err := dotsql.LoadFromString("-- name: a\nSELECT 'dotsql a'\n-- name: b\nSELECT 'dotsql b'")
+	require.Nil(t, err)
+
+	r := NewRegistry()
+	require.Nil(t, r.Add("c", "SELECT 'registry c'", Annotations{{Key: "tags", Value: "x"}}))
+
+	s := Composite(MapSource{"a": "SELECT 'map a'"}, d, r)
+
+	q, err := s.Raw("a")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT 'map a'", q)
+
+	q, err = s.Raw("c")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT 'registry c'", q)
+
+	_, err = s.Raw("missing")
+	assert.Equal(t, &QueryError{Name: "missing", Err: ErrNotFound}, err)
+
+	_, err = Composite().Raw("missing")
+	assert.Equal(t, &QueryError{Name: "missing", Err: ErrNotFound}, err)
+
+	assert.Equal(t, map[string]string{
+		"a": "SELECT 'map a'",
+		"b": "SELECT 'dotsql b'",
+		"c": "SELECT 'registry c'",
+	}, s.QueryMap())
+
+	dot := WrapSource(s)
+	assert.Equal(t, []string{"a", "b", "c"}, dot.Names())
+
+	aa, err := dot.Annotations("c")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"x"}, aa.Tags())
+
+	aa, err = dot.Annotations("b")
+	require.Nil(t, err)
+	assert.Empty(t, aa)
+}
+
+func TestWrapSource(t *testing.T) {
+	d, err := dotsql.LoadFromString("-- name: a\nSELECT 1")
+	require.Nil(t, err)
+
+	dot := WrapSource(d)
+	assert.Equal(t, d, dot.DotSql)
+	assert.Equal(t, d, dot.Source())
+
+	dot = WrapSource(MapSource{})
+	assert.NotNil(t, dot.DotSql)
+
+	// instances created without a source use the embedded DotSql
+	dot = &DotSqlx{DotSql: d}
+	q, err := dot.Raw("a")
+	require.Nil(t, err)
+	assert.Equal(t, "SELECT 1", q)
+}
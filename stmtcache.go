@@ -0,0 +1,255 @@
+package dotsqlx
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// StmtCache lazily prepares dotsql named queries once per database and
+// reuses the prepared statements. When the cache is full, the least
+// recently used statement is evicted; it is closed as soon as no caller
+// uses it anymore.
+//
+// Statements broken by bad connections need no special handling, since
+// database/sql prepares them again on another connection.
+type StmtCache struct {
+	dot  *DotSqlx
+	size int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[stmtKey]*list.Element
+}
+
+// stmtKey identifies a prepared statement.
+type stmtKey struct {
+	db   *sqlx.DB
+	name string
+}
+
+// stmtEntry is a single prepared statement held by the cache.
+type stmtEntry struct {
+	key  stmtKey
+	stmt *sqlx.Stmt
+
+	// refs is the number of callers using the statement.
+	refs    int
+	evicted bool
+}
+
+// NewStmtCache creates a new StmtCache instance holding at most size
+// prepared statements. If size is zero or negative, statements are never
+// evicted.
+func NewStmtCache(d *DotSqlx, size int) *StmtCache {
+	return &StmtCache{
+		dot:   d,
+		size:  size,
+		ll:    list.New(),
+		items: make(map[stmtKey]*list.Element),
+	}
+}
+
+// Len returns the number of cached statements.
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+// PreparexContext returns the statement of dotsql named query prepared on
+// db, preparing it if it is not cached yet, along with a function that
+// releases it. The statement must not be closed by the caller nor used
+// after it is released; if it is evicted in the meantime, it is closed
+// once all callers released it.
+func (c *StmtCache) PreparexContext(ctx context.Context, db *sqlx.DB, name string) (*sqlx.Stmt, func(), error) {
+	e, err := c.acquire(ctx, db, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e.stmt, c.releaser(e), nil
+}
+
+// TxStmtxContext returns the cached statement of dotsql named query bound
+// to tx, which must have been started on db, along with a function that
+// releases it. The statement must not be used after it is released, which
+// should happen before tx ends.
+func (c *StmtCache) TxStmtxContext(ctx context.Context, tx *sqlx.Tx, db *sqlx.DB, name string) (*sqlx.Stmt, func(), error) {
+	e, err := c.acquire(ctx, db, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tx.StmtxContext(ctx, e.stmt), c.releaser(e), nil
+}
+
+// GetContext executes the cached statement of dotsql named query via
+// jmoiron/sqlx's Stmt.GetContext().
+func (c *StmtCache) GetContext(ctx context.Context, db *sqlx.DB, dest interface{}, name string, args ...interface{}) error {
+	return c.do(ctx, db, name, func(stmt *sqlx.Stmt) error {
+		return stmt.GetContext(ctx, dest, args...)
+	})
+}
+
+// SelectContext executes the cached statement of dotsql named query via
+// jmoiron/sqlx's Stmt.SelectContext().
+func (c *StmtCache) SelectContext(ctx context.Context, db *sqlx.DB, dest interface{}, name string, args ...interface{}) error {
+	return c.do(ctx, db, name, func(stmt *sqlx.Stmt) error {
+		return stmt.SelectContext(ctx, dest, args...)
+	})
+}
+
+// ExecContext executes the cached statement of dotsql named query via
+// jmoiron/sqlx's Stmt.ExecContext().
+func (c *StmtCache) ExecContext(ctx context.Context, db *sqlx.DB, name string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := c.do(ctx, db, name, func(stmt *sqlx.Stmt) error {
+		var err error
+		res, err = stmt.ExecContext(ctx, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Close evicts all cached statements and closes the ones that are not in
+// use; the others are closed once they are released. The first
+// encountered error is returned.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for e := c.ll.Front(); e != nil; e = c.ll.Front() {
+		if cerr := c.remove(e); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// do calls fn with the cached statement of dotsql named query, which is
+// held until fn returns.
+func (c *StmtCache) do(ctx context.Context, db *sqlx.DB, name string, fn func(stmt *sqlx.Stmt) error) error {
+	e, err := c.acquire(ctx, db, name)
+	if err != nil {
+		return err
+	}
+
+	defer c.release(e)
+
+	return fn(e.stmt)
+}
+
+// acquire returns the cached statement of dotsql named query, preparing it
+// if it is not cached yet, and marks it as used until it is released.
+func (c *StmtCache) acquire(ctx context.Context, db *sqlx.DB, name string) (*stmtEntry, error) {
+	key := stmtKey{db: db, name: name}
+
+	if e := c.lookup(key); e != nil {
+		c.dot.track(name)
+		return e, nil
+	}
+
+	stmt, err := c.dot.PreparexContext(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.store(key, stmt, 1), nil
+}
+
+// releaser returns a function that releases the statement once, no matter
+// how many times it is called.
+func (c *StmtCache) releaser(e *stmtEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.release(e)
+		})
+	}
+}
+
+// release marks the statement as no longer used by a caller and closes it
+// if it was evicted and this was its last user.
+func (c *StmtCache) release(e *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.refs--
+	if e.evicted && e.refs == 0 {
+		e.stmt.Close()
+	}
+}
+
+// lookup returns the cached statement, marks it as recently used and adds
+// a user to it.
+func (c *StmtCache) lookup(key stmtKey) *stmtEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+
+	c.ll.MoveToFront(e)
+
+	se := e.Value.(*stmtEntry)
+	se.refs++
+
+	return se
+}
+
+// store caches the statement with the number of its users, evicting the
+// least recently used one if the cache is full. If the key was cached
+// concurrently, the provided statement is closed and the users are added
+// to the cached one.
+func (c *StmtCache) store(key stmtKey, stmt *sqlx.Stmt, refs int) *stmtEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		stmt.Close()
+		c.ll.MoveToFront(e)
+
+		se := e.Value.(*stmtEntry)
+		se.refs += refs
+
+		return se
+	}
+
+	se := &stmtEntry{key: key, stmt: stmt, refs: refs}
+	c.items[key] = c.ll.PushFront(se)
+
+	if c.size > 0 && c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+
+	return se
+}
+
+// remove evicts the statement of the list element and closes it if it is
+// not in use. The caller must hold the lock.
+func (c *StmtCache) remove(e *list.Element) error {
+	se := e.Value.(*stmtEntry)
+
+	c.ll.Remove(e)
+	delete(c.items, se.key)
+	se.evicted = true
+
+	if se.refs == 0 {
+		return se.stmt.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package dotsqlx
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const stmtQueries = queries + `
+
+-- name: select_all
+SELECT nr FROM numbers WHERE nr >= ? ORDER BY nr`
+
+func TestStmtCachePreparexContext(t *testing.T) {
+	dot := newDot(t, queries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	c := NewStmtCache(dot, 1)
+	defer c.Close()
+
+	// query not found
+	stmt, release, err := c.PreparexContext(context.Background(), db, "select123")
+	assert.Nil(t, stmt)
+	assert.Nil(t, release)
+	assert.NotNil(t, err)
+	assert.Zero(t, c.Len())
+
+	// error returned by db
+	stmt, _, err = c.PreparexContext(context.Background(), db, "insert")
+	assert.Nil(t, stmt)
+	assert.NotNil(t, err)
+	assert.Zero(t, c.Len())
+
+	// successful call
+	stmt, release, err = c.PreparexContext(context.Background(), db, "select")
+	require.Nil(t, err)
+	assert.NotNil(t, stmt)
+	assert.Equal(t, 1, c.Len())
+
+	// cached statement
+	stmt2, release2, err := c.PreparexContext(context.Background(), db, "select")
+	require.Nil(t, err)
+	assert.True(t, stmt == stmt2)
+	release2()
+	release2()
+
+	// cached per db
+	db2 := newDB(t, 1)
+	defer db2.Close()
+
+	stmt2, release2, err = c.PreparexContext(context.Background(), db2, "select")
+	require.Nil(t, err)
+	assert.False(t, stmt == stmt2)
+	release2()
+
+	// least recently used statement evicted but not closed while in use
+	assert.Equal(t, 1, c.Len())
+	var nr int
+	assert.Nil(t, stmt.Get(&nr, 1))
+
+	// closed once released
+	release()
+	assert.NotNil(t, stmt.Get(&nr, 1))
+
+	// closed right away if not in use
+	stmt, release, err = c.PreparexContext(context.Background(), db, "select")
+	require.Nil(t, err)
+	release()
+
+	_, release2, err = c.PreparexContext(context.Background(), db2, "select")
+	require.Nil(t, err)
+	release2()
+	assert.NotNil(t, stmt.Get(&nr, 1))
+}
+
+func TestStmtCacheTxStmtxContext(t *testing.T) {
+	dot := newDot(t, queries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	c := NewStmtCache(dot, 0)
+	defer c.Close()
+
+	// query not found
+	tx := db.MustBegin()
+	stmt, _, err := c.TxStmtxContext(context.Background(), tx, db, "select123")
+	assert.Nil(t, stmt)
+	assert.NotNil(t, err)
+	require.Nil(t, tx.Rollback())
+
+	// successful call
+	_, release, err := c.PreparexContext(context.Background(), db, "select")
+	require.Nil(t, err)
+	release()
+
+	tx = db.MustBegin()
+	defer tx.Rollback()
+
+	stmt, release, err = c.TxStmtxContext(context.Background(), tx, db, "select")
+	require.Nil(t, err)
+	defer release()
+
+	var nr int
+	require.Nil(t, stmt.Get(&nr, 2))
+	assert.Equal(t, 2, nr)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestStmtCacheGetContext(t *testing.T) {
+	tr := NewTracker()
+	dot := newDot(t, queries).WithTracker(tr)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	c := NewStmtCache(dot, 0)
+	defer c.Close()
+
+	// query not found
+	var nr number
+	err := c.GetContext(context.Background(), db, &nr, "select123", 1)
+	assert.NotNil(t, err)
+
+	// successful call
+	err = c.GetContext(context.Background(), db, &nr, "select", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, nr.Nr)
+
+	// cached statement executions recorded
+	err = c.GetContext(context.Background(), db, &nr, "select", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"select": 2}, tr.Executed())
+}
+
+func TestStmtCacheSelectContext(t *testing.T) {
+	dot := newDot(t, queries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	c := NewStmtCache(dot, 0)
+	defer c.Close()
+
+	// query not found
+	var nrs []number
+	err := c.SelectContext(context.Background(), db, &nrs, "select123", 1)
+	assert.NotNil(t, err)
+
+	// successful call
+	err = c.SelectContext(context.Background(), db, &nrs, "select", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []number{{2}}, nrs)
+}
+
+func TestStmtCacheExecContext(t *testing.T) {
+	dot := newDot(t, `
+-- name: insert
+INSERT INTO numbers (nr) VALUES(?)`)
+	db := newDB(t, 0)
+	defer db.Close()
+
+	c := NewStmtCache(dot, 0)
+	defer c.Close()
+
+	// query not found
+	res, err := c.ExecContext(context.Background(), db, "insert123", 1)
+	assert.Nil(t, res)
+	assert.NotNil(t, err)
+
+	// successful call
+	res, err = c.ExecContext(context.Background(), db, "insert", 1)
+	require.Nil(t, err)
+	n, err := res.RowsAffected()
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestStmtCacheConcurrentEviction(t *testing.T) {
+	dot := newDot(t, stmtQueries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	c := NewStmtCache(dot, 1)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16*50)
+
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				var nrs []number
+				name := "select"
+				if (i+j)%2 == 0 {
+					name = "select_all"
+				}
+
+				if err := c.SelectContext(context.Background(), db, &nrs, name, 1); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestStmtCacheClose(t *testing.T) {
+	dot := newDot(t, stmtQueries)
+	db := newDB(t, 3)
+	defer db.Close()
+
+	c := NewStmtCache(dot, 0)
+
+	stmt, release, err := c.PreparexContext(context.Background(), db, "select")
+	require.Nil(t, err)
+
+	stmt2, release2, err := c.PreparexContext(context.Background(), db, "select_all")
+	require.Nil(t, err)
+	release2()
+
+	assert.Nil(t, c.Close())
+	assert.Zero(t, c.Len())
+
+	// statements in use are closed once released
+	var nr int
+	assert.NotNil(t, stmt2.Get(&nr, 1))
+	assert.Nil(t, stmt.Get(&nr, 1))
+
+	release()
+	assert.NotNil(t, stmt.Get(&nr, 1))
+}
@@ -0,0 +1,334 @@
+package dotsqlx
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/qustavo/dotsql"
+)
+
+// DefaultRenderCacheSize is the number of rendered queries cached when
+// TemplateOptions.CacheSize is zero.
+const DefaultRenderCacheSize = 1000
+
+// TemplateOptions holds what templated queries may interpolate.
+type TemplateOptions struct {
+	// Idents are the identifiers, e.g. column or table names, that can be
+	// inserted with `{{ ident .x }}`.
+	Idents []string
+
+	// Fragments are the SQL fragments, keyed by name, that can be inserted
+	// with `{{ fragment "name" }}`.
+	Fragments map[string]string
+
+	// CacheSize is the maximum number of rendered queries cached; the least
+	// recently used one is evicted when the cache is full. If zero,
+	// DefaultRenderCacheSize is used. If negative, rendered queries are
+	// not cached.
+	CacheSize int
+}
+
+// Templates renders dotsql named queries as text/template templates:
+//
+//	-- name: select_users
+//	SELECT * FROM users
+//	WHERE age >= ?
+//	{{ if .active }}{{ fragment "active_only" }}{{ end }}
+//	ORDER BY {{ ident .sort }}
+//
+// Only whitelisted identifiers and registered fragments can be written
+// into the query; any other output action is rejected when the template is
+// parsed, so values must still be passed as bind parameters. Rendered
+// queries are cached per query and distinct data, compared by the types and
+// values of its entries, so data should only hold values such as strings,
+// numbers and booleans.
+type Templates struct {
+	dot       *DotSqlx
+	idents    map[string]bool
+	fragments map[string]string
+	size      int
+
+	mu       sync.Mutex
+	parsed   map[string]*template.Template
+	ll       *list.List
+	rendered map[string]*list.Element
+}
+
+// renderEntry is a single rendered query held by the cache.
+type renderEntry struct {
+	key   string
+	query string
+}
+
+// NewTemplates creates a new Templates instance.
+func NewTemplates(d *DotSqlx, opts TemplateOptions) *Templates {
+	t := &Templates{
+		dot:       d,
+		idents:    make(map[string]bool, len(opts.Idents)),
+		fragments: make(map[string]string, len(opts.Fragments)),
+		size:      opts.CacheSize,
+		parsed:    make(map[string]*template.Template),
+		ll:        list.New(),
+		rendered:  make(map[string]*list.Element),
+	}
+
+	if t.size == 0 {
+		t.size = DefaultRenderCacheSize
+	}
+
+	for _, id := range opts.Idents {
+		t.idents[id] = true
+	}
+
+	for name, frag := range opts.Fragments {
+		t.fragments[name] = frag
+	}
+
+	return t
+}
+
+// Render executes the template of dotsql named query with data.
+func (t *Templates) Render(name string, data map[string]interface{}) (string, error) {
+	query, err := t.dot.Raw(name)
+	if err != nil {
+		return "", err
+	}
+
+	return t.render(name, query, data)
+}
+
+// RenderFor executes the template of the variant of dotsql named query
+// for the dialect of the driver with data.
+func (t *Templates) RenderFor(driverName, name string, data map[string]interface{}) (string, error) {
+	query, err := t.dot.RawFor(driverName, name)
+	if err != nil {
+		return "", err
+	}
+
+	return t.render(name, query, data)
+}
+
+// GetContext renders dotsql named query with data and executes it via
+// jmoiron/sqlx's GetContext().
+func (t *Templates) GetContext(ctx context.Context, dbx GetterContext, dest interface{}, name string, data map[string]interface{}, args ...interface{}) error {
+	query, err := t.dbRender(dbx, name, data)
+	if err != nil {
+		return err
+	}
+
+	return dbx.GetContext(ctx, dest, query, args...)
+}
+
+// SelectContext renders dotsql named query with data and executes it via
+// jmoiron/sqlx's SelectContext().
+func (t *Templates) SelectContext(ctx context.Context, dbx SelecterContext, dest interface{}, name string, data map[string]interface{}, args ...interface{}) error {
+	query, err := t.dbRender(dbx, name, data)
+	if err != nil {
+		return err
+	}
+
+	return dbx.SelectContext(ctx, dest, query, args...)
+}
+
+// ExecContext renders dotsql named query with data and executes it via
+// database/sql's ExecContext().
+func (t *Templates) ExecContext(ctx context.Context, db dotsql.ExecerContext, name string, data map[string]interface{}, args ...interface{}) (sql.Result, error) {
+	query, err := t.dbRender(db, name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.ExecContext(ctx, query, args...)
+}
+
+// dbRender renders the variant of dotsql named query for the executor's
+// dialect.
+func (t *Templates) dbRender(db interface{}, name string, data map[string]interface{}) (string, error) {
+	query, err := t.dot.raw(db, name)
+	if err != nil {
+		return "", err
+	}
+
+	return t.render(name, query, data)
+}
+
+// render executes the query template, using the cache when possible.
+func (t *Templates) render(name, query string, data map[string]interface{}) (string, error) {
+	key := renderKey(query, data)
+
+	t.mu.Lock()
+	e, ok := t.rendered[key]
+	if ok {
+		t.ll.MoveToFront(e)
+	}
+	tmpl := t.parsed[query]
+	t.mu.Unlock()
+
+	if ok {
+		return e.Value.(*renderEntry).query, nil
+	}
+
+	if tmpl == nil {
+		var err error
+		if tmpl, err = t.parse(name, query); err != nil {
+			return "", &QueryError{Name: name, Err: err}
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", &QueryError{Name: name, Err: err}
+	}
+
+	res := b.String()
+
+	t.mu.Lock()
+	t.parsed[query] = tmpl
+	t.store(key, res)
+	t.mu.Unlock()
+
+	return res, nil
+}
+
+// store caches the rendered query, evicting the least recently used one if
+// the cache is full. The caller must hold the lock.
+func (t *Templates) store(key, query string) {
+	if t.size < 0 {
+		return
+	}
+
+	if e, ok := t.rendered[key]; ok {
+		t.ll.MoveToFront(e)
+		return
+	}
+
+	t.rendered[key] = t.ll.PushFront(&renderEntry{key: key, query: query})
+
+	if t.ll.Len() > t.size {
+		e := t.ll.Back()
+		t.ll.Remove(e)
+		delete(t.rendered, e.Value.(*renderEntry).key)
+	}
+}
+
+// renderKey returns the cache key of the query rendered with data. Entries
+// are written in key order along with their types, so that e.g. false and
+// "false" don't share a key.
+func renderKey(query string, data map[string]interface{}) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(query)
+
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\x00%q:%T:%#v", k, data[k], data[k])
+	}
+
+	return b.String()
+}
+
+// parse parses the query template and checks that it only outputs
+// whitelisted identifiers and registered fragments.
+func (t *Templates) parse(name, query string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"ident":    t.ident,
+		"fragment": t.fragment,
+	}).Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("dotsqlx: template definitions are not allowed")
+	}
+
+	if err := checkNode(tmpl.Tree.Root); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// ident returns the identifier if it is whitelisted.
+func (t *Templates) ident(v interface{}) (string, error) {
+	id := fmt.Sprint(v)
+	if !t.idents[id] {
+		return "", fmt.Errorf("dotsqlx: identifier %q is not allowed", id)
+	}
+
+	return id, nil
+}
+
+// fragment returns the registered fragment.
+func (t *Templates) fragment(name string) (string, error) {
+	frag, ok := t.fragments[name]
+	if !ok {
+		return "", fmt.Errorf("dotsqlx: fragment %q is not registered", name)
+	}
+
+	return frag, nil
+}
+
+// checkNode returns an error if the node, or any of its children, writes
+// anything other than the result of ident or fragment.
+func checkNode(n parse.Node) error {
+	switch n := n.(type) {
+	case nil:
+		return nil
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+
+		for _, c := range n.Nodes {
+			if err := checkNode(c); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return nil
+		}
+
+		cmd := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok && (id.Ident == "ident" || id.Ident == "fragment") {
+			return nil
+		}
+
+		return fmt.Errorf("dotsqlx: %s: only ident and fragment results can be interpolated", n)
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		return fmt.Errorf("dotsqlx: %s: template calls are not allowed", n)
+	}
+
+	return nil
+}
+
+func checkBranch(n *parse.BranchNode) error {
+	if err := checkNode(n.List); err != nil {
+		return err
+	}
+
+	if n.ElseList == nil {
+		return nil
+	}
+
+	return checkNode(n.ElseList)
+}
@@ -0,0 +1,181 @@
+package dotsqlx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const templateQueries = `
+-- name: select_numbers
+SELECT nr FROM numbers
+WHERE nr >= ?
+{{ if .even }}{{ fragment "even" }}{{ end }}
+ORDER BY {{ ident .sort }} {{ ident .dir }}
+
+-- name: delete_numbers
+DELETE FROM {{ ident .table }} WHERE nr < ?
+
+-- name: select_value
+SELECT {{ .value }} FROM numbers
+
+-- name: select_printf
+SELECT {{ ident .sort | printf "%s" }} FROM numbers
+
+-- name: select_define
+{{ define "x" }}nr{{ end }}SELECT {{ template "x" }} FROM numbers
+
+-- name: select_invalid
+SELECT {{ if }} FROM numbers
+`
+
+func newTemplates(t *testing.T) *Templates {
+	return NewTemplates(newDot(t, templateQueries), TemplateOptions{
+		Idents:    []string{"nr", "numbers", "ASC", "DESC"},
+		Fragments: map[string]string{"even": "AND nr % 2 = 0"},
+	})
+}
+
+func TestTemplatesRender(t *testing.T) {
+	cc := map[string]struct {
+		Name  string
+		Data  map[string]interface{}
+		Query string
+		Err   string
+	}{
+		"query not found": {
+			Name: "missing",
+			Err:  "missing: dotsqlx: query not found",
+		},
+		"ident and fragment": {
+			Name:  "select_numbers",
+			Data:  map[string]interface{}{"even": true, "sort": "nr", "dir": "DESC"},
+			Query: "SELECT nr FROM numbers\nWHERE nr >= ?\nAND nr % 2 = 0\nORDER BY nr DESC",
+		},
+		"ident without fragment": {
+			Name:  "select_numbers",
+			Data:  map[string]interface{}{"even": false, "sort": "nr", "dir": "ASC"},
+			Query: "SELECT nr FROM numbers\nWHERE nr >= ?\n\nORDER BY nr ASC",
+		},
+		"ident not allowed": {
+			Name: "delete_numbers",
+			Data: map[string]interface{}{"table": "numbers; DROP TABLE numbers"},
+			Err:  `identifier "numbers; DROP TABLE numbers" is not allowed`,
+		},
+		"missing key": {
+			Name: "delete_numbers",
+			Data: map[string]interface{}{},
+			Err:  `map has no entry for key "table"`,
+		},
+		"plain value": {
+			Name: "select_value",
+			Err:  "only ident and fragment results can be interpolated",
+		},
+		"ident piped": {
+			Name: "select_printf",
+			Err:  "only ident and fragment results can be interpolated",
+		},
+		"template definition": {
+			Name: "select_define",
+			Err:  "template definitions are not allowed",
+		},
+		"invalid template": {
+			Name: "select_invalid",
+			Err:  "missing value for if",
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := newTemplates(t).Render(c.Name, c.Data)
+			if c.Err != "" {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), c.Err)
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, c.Query, query)
+		})
+	}
+}
+
+func TestTemplatesCache(t *testing.T) {
+	tt := newTemplates(t)
+
+	data := map[string]interface{}{"even": true, "sort": "nr", "dir": "ASC"}
+	_, err := tt.Render("select_numbers", data)
+	require.Nil(t, err)
+
+	_, err = tt.Render("select_numbers", data)
+	require.Nil(t, err)
+
+	_, err = tt.Render("select_numbers", map[string]interface{}{"even": false, "sort": "nr", "dir": "ASC"})
+	require.Nil(t, err)
+
+	assert.Len(t, tt.parsed, 1)
+	assert.Len(t, tt.rendered, 2)
+
+	// values of different types
+	res, err := tt.Render("select_numbers", map[string]interface{}{"even": "false", "sort": "nr", "dir": "ASC"})
+	require.Nil(t, err)
+	assert.Contains(t, res, "AND nr % 2 = 0")
+	assert.Len(t, tt.rendered, 3)
+
+	// least recently used query evicted
+	tt = NewTemplates(newDot(t, templateQueries), TemplateOptions{
+		Idents:    []string{"nr", "ASC", "DESC"},
+		CacheSize: 1,
+	})
+
+	_, err = tt.Render("select_numbers", map[string]interface{}{"even": false, "sort": "nr", "dir": "ASC"})
+	require.Nil(t, err)
+
+	res, err = tt.Render("select_numbers", map[string]interface{}{"even": false, "sort": "nr", "dir": "DESC"})
+	require.Nil(t, err)
+	assert.Len(t, tt.rendered, 1)
+	assert.Equal(t, 1, tt.ll.Len())
+	assert.Equal(t, res, tt.ll.Front().Value.(*renderEntry).query)
+
+	// caching disabled
+	tt = NewTemplates(newDot(t, templateQueries), TemplateOptions{
+		Idents:    []string{"nr", "ASC"},
+		CacheSize: -1,
+	})
+
+	_, err = tt.Render("select_numbers", map[string]interface{}{"even": false, "sort": "nr", "dir": "ASC"})
+	require.Nil(t, err)
+	assert.Empty(t, tt.rendered)
+}
+
+func TestTemplatesExecutors(t *testing.T) {
+	db := newDB(t, 6)
+	tt := newTemplates(t)
+	ctx := context.Background()
+
+	var nn []int
+	require.Nil(t, tt.SelectContext(ctx, db, &nn, "select_numbers",
+		map[string]interface{}{"even": true, "sort": "nr", "dir": "DESC"}, 1))
+	assert.Equal(t, []int{4, 2}, nn)
+
+	res, err := tt.ExecContext(ctx, db, "delete_numbers", map[string]interface{}{"table": "numbers"}, 3)
+	require.Nil(t, err)
+
+	n, err := res.RowsAffected()
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+
+	var nr int
+	require.Nil(t, tt.GetContext(ctx, db, &nr, "select_numbers",
+		map[string]interface{}{"even": false, "sort": "nr", "dir": "ASC"}, 0))
+	assert.Equal(t, 3, nr)
+
+	err = tt.GetContext(ctx, db, &nr, "select_value", nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,17 @@
+-- name: number_columns
+-- fragment: true
+nr,
+nr * 2 AS double
+
+-- name: even_only
+-- fragment: true
+WHERE nr % 2 = 0
+
+-- name: select_even
+SELECT
+-- include: number_columns
+FROM numbers
+-- include: even_only
+
+-- name: select_nr
+SELECT nr FROM numbers
@@ -0,0 +1,2 @@
+-- name: select_nr
+SELECT nr FROM numbers ORDER BY nr DESC
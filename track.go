@@ -0,0 +1,55 @@
+package dotsqlx
+
+import (
+	"sort"
+	"sync"
+)
+
+// Tracker records the names of executed dotsql named queries, e.g. during
+// a test run, so that queries never executed can be reported.
+type Tracker struct {
+	mu    sync.Mutex
+	names map[string]int
+}
+
+// NewTracker creates a new Tracker instance.
+func NewTracker() *Tracker {
+	return &Tracker{names: make(map[string]int)}
+}
+
+// Track records a single execution of the named query.
+func (t *Tracker) Track(name string) {
+	t.mu.Lock()
+	t.names[name]++
+	t.mu.Unlock()
+}
+
+// Executed returns the number of executions per query name.
+func (t *Tracker) Executed() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := make(map[string]int, len(t.names))
+	for name, n := range t.names {
+		res[name] = n
+	}
+
+	return res
+}
+
+// Unused returns the sorted names that were never executed.
+func (t *Tracker) Unused(names []string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var res []string
+	for _, name := range names {
+		if t.names[name] == 0 {
+			res = append(res, name)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
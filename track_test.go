@@ -0,0 +1,33 @@
+package dotsqlx
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tr.Track("select")
+		}()
+	}
+
+	wg.Wait()
+	tr.Track("insert")
+
+	ee := tr.Executed()
+	assert.Equal(t, map[string]int{"select": 10, "insert": 1}, ee)
+
+	ee["update"] = 1
+	assert.Len(t, tr.Executed(), 2)
+
+	assert.Equal(t, []string{"delete", "update"}, tr.Unused([]string{"update", "select", "insert", "delete"}))
+	assert.Nil(t, tr.Unused([]string{"select"}))
+}
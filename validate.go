@@ -0,0 +1,146 @@
+package dotsqlx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	annotationsMu    sync.RWMutex
+	knownAnnotations = map[string]bool{
+		"tags":        true,
+		"param":       true,
+		"returns":     true,
+		"import":      true,
+		"dialect":     true,
+		"include":     true,
+		"fragment":    true,
+		"description": true,
+		"timeout":     true,
+		"owner":       true,
+		"depends":     true,
+	}
+)
+
+// RegisterAnnotation marks annotation keys as known, so that Validate
+// doesn't report them.
+func RegisterAnnotation(keys ...string) {
+	annotationsMu.Lock()
+	defer annotationsMu.Unlock()
+
+	for _, k := range keys {
+		knownAnnotations[k] = true
+	}
+}
+
+// knownAnnotation reports whether the annotation key is registered.
+func knownAnnotation(key string) bool {
+	annotationsMu.RLock()
+	defer annotationsMu.RUnlock()
+
+	return knownAnnotations[key]
+}
+
+// bindTypeNames holds the names of jmoiron/sqlx bindvar types.
+var bindTypeNames = map[int]string{
+	sqlx.QUESTION: "QUESTION",
+	sqlx.DOLLAR:   "DOLLAR",
+	sqlx.NAMED:    "NAMED",
+	sqlx.AT:       "AT",
+}
+
+// Issue is a problem found in a named query definition.
+type Issue struct {
+	Definition Definition
+	Message    string
+}
+
+// String returns the source location and name of the query followed by
+// the issue message.
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Definition.Location(), i.Definition.Name, i.Message)
+}
+
+// Validate checks the structure of named query definitions and returns all
+// found issues: names defined twice in the same file, empty bodies, mixed bindvar styles,
+// unbalanced quotes or comments, unknown annotations, unknown includes and
+// include cycles.
+func Validate(dd []Definition) []Issue {
+	var (
+		ii   []Issue
+		seen = make(map[string]Definition)
+	)
+
+	for _, d := range dd {
+		report := func(format string, args ...interface{}) {
+			ii = append(ii, Issue{Definition: d, Message: fmt.Sprintf(format, args...)})
+		}
+
+		// Dialect-specific variants share the name of the query. As with
+		// Load, a definition in a later file overrides the earlier ones.
+		key := d.Name + "\x00" + d.Annotations.Get("dialect")
+		if first, ok := seen[key]; ok && first.File == d.File {
+			report("duplicate name, first defined at %s", first.Location())
+		} else {
+			seen[key] = d
+		}
+
+		if isEmpty(d.SQL) {
+			report("empty body")
+		}
+
+		if _, ok := lex(d.SQL); !ok {
+			report("unbalanced quotes or comments")
+		}
+
+		if bb := BindTypes(d.SQL); len(bb) > 1 {
+			nn := make([]string, len(bb))
+			for i, bt := range bb {
+				nn[i] = bindTypeNames[bt]
+			}
+
+			report("mixed bindvar styles: %s", strings.Join(nn, ", "))
+		}
+
+		for _, a := range d.Annotations {
+			if !knownAnnotation(a.Key) {
+				report("unknown annotation %q", a.Key)
+			}
+		}
+	}
+
+	_, rii := resolveIncludes(dd)
+	ii = append(ii, rii...)
+
+	sort.SliceStable(ii, func(i, j int) bool {
+		a, b := ii[i].Definition, ii[j].Definition
+		if a.File != b.File {
+			return a.File < b.File
+		}
+
+		return a.Line < b.Line
+	})
+
+	return ii
+}
+
+// isEmpty reports whether the query has no code besides comments other
+// than includes.
+func isEmpty(query string) bool {
+	tt, _ := lex(query)
+	for _, t := range tt {
+		if t.kind == tokenComment && includeRe.MatchString(t.text) {
+			return false
+		}
+
+		if t.kind != tokenComment && strings.TrimSpace(t.text) != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,84 @@
+package dotsqlx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueString(t *testing.T) {
+	i := Issue{Definition: Definition{Name: "a", File: "q.sql", Line: 2}, Message: "empty body"}
+	assert.Equal(t, "q.sql:2: a: empty body", i.String())
+}
+
+func TestValidate(t *testing.T) {
+	dd, err := ParseDefinitions(strings.NewReader(`
+-- name: valid
+-- tags: a
+SELECT * FROM t WHERE a = ? AND b = '$1'
+
+-- name: empty
+-- tags: a
+
+-- name: unbalanced
+SELECT 'a
+
+-- name: mixed
+SELECT * FROM t WHERE a = ? AND b = :b
+
+-- name: unknown
+-- team: reports
+SELECT 1
+
+-- name: valid
+SELECT 2`), "q.sql")
+	require.Nil(t, err)
+
+	ii := Validate(dd)
+	require.Len(t, ii, 5)
+	assert.Equal(t, "q.sql:6: empty: empty body", ii[0].String())
+	assert.Equal(t, "q.sql:9: unbalanced: unbalanced quotes or comments", ii[1].String())
+	assert.Equal(t, "q.sql:12: mixed: mixed bindvar styles: QUESTION, NAMED", ii[2].String())
+	assert.Equal(t, `q.sql:15: unknown: unknown annotation "team"`, ii[3].String())
+	assert.Equal(t, "q.sql:19: valid: duplicate name, first defined at q.sql:2", ii[4].String())
+
+	RegisterAnnotation("team")
+	defer func() {
+		annotationsMu.Lock()
+		delete(knownAnnotations, "team")
+		annotationsMu.Unlock()
+	}()
+
+	assert.Len(t, Validate(dd), 4)
+}
+
+func TestValidateDialects(t *testing.T) {
+	dd, err := ParseDefinitions(strings.NewReader(`
+-- name: now
+-- dialect: postgres
+SELECT now()
+
+-- name: now
+-- dialect: sqlite
+SELECT datetime('now')
+
+-- name: now
+SELECT CURRENT_TIMESTAMP
+
+-- name: now
+-- dialect: sqlite
+SELECT 1`), "q.sql")
+	require.Nil(t, err)
+
+	ii := Validate(dd)
+	require.Len(t, ii, 1)
+	assert.Equal(t, "q.sql:13: now: duplicate name, first defined at q.sql:6", ii[0].String())
+}
+
+func TestValidateOverrides(t *testing.T) {
+	dd, err := LoadDefinitions("testdata/includes.sql", "testdata/overrides.sql")
+	require.Nil(t, err)
+	assert.Empty(t, Validate(dd))
+}